@@ -3,9 +3,7 @@ package deepseek
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"net/http"
-	"strconv"
 
 	deepseek "github.com/p9966/go-deepseek/internal"
 )
@@ -24,6 +22,44 @@ type BalanceInfo struct {
 
 const BalanceSuffix = "/user/balance"
 
+// Total returns the total balance as an exact decimal.
+func (b BalanceInfo) Total() (Decimal, error) {
+	return ParseDecimal(b.TotalBalance)
+}
+
+// Granted returns the granted balance as an exact decimal.
+func (b BalanceInfo) Granted() (Decimal, error) {
+	return ParseDecimal(b.GrantedBalance)
+}
+
+// ToppedUp returns the topped up balance as an exact decimal.
+func (b BalanceInfo) ToppedUp() (Decimal, error) {
+	return ParseDecimal(b.ToppedUpBalance)
+}
+
+// Balance returns the balance held in currency, e.g. "CNY" or "USD".
+func (r *BalanceResponse) Balance(currency string) (BalanceInfo, bool) {
+	for _, info := range r.BalanceInfos {
+		if info.Currency == currency {
+			return info, true
+		}
+	}
+	return BalanceInfo{}, false
+}
+
+// Totals returns the total balance per currency.
+func (r *BalanceResponse) Totals() (map[string]Decimal, error) {
+	totals := make(map[string]Decimal, len(r.BalanceInfos))
+	for _, info := range r.BalanceInfos {
+		total, err := info.Total()
+		if err != nil {
+			return nil, err
+		}
+		totals[info.Currency] = total
+	}
+	return totals, nil
+}
+
 func (c *Client) GetBalance(ctx context.Context) (*BalanceResponse, error) {
 	request, err := deepseek.NewRequestBuilder(c.AuthToken).SetBaseUrl(c.BaseUrl).SetPath(BalanceSuffix).SetMethod(http.MethodGet).Build(ctx)
 	if err != nil {
@@ -37,7 +73,7 @@ func (c *Client) GetBalance(ctx context.Context) (*BalanceResponse, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("unexpected status code:" + strconv.Itoa(resp.StatusCode))
+		return nil, newAPIError(resp)
 	}
 
 	var result BalanceResponse
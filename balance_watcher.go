@@ -0,0 +1,236 @@
+package deepseek
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// BalanceEventType identifies why a BalanceEvent was emitted.
+type BalanceEventType string
+
+const (
+	BalanceEventAvailability BalanceEventType = "availability" // IsAvailable flipped
+	BalanceEventThreshold    BalanceEventType = "threshold"    // The balance crossed a configured threshold
+)
+
+// BalanceEvent is emitted by a BalanceWatcher.
+type BalanceEvent struct {
+	Type       BalanceEventType
+	Time       time.Time
+	Currency   string
+	Balance    Decimal
+	Available  bool
+	Threshold  Decimal       // Threshold crossed, for threshold events
+	Below      bool          // Whether the balance fell below Threshold (true) or recovered above it (false)
+	BurnRate   float64       // Spend per hour over the watch window, 0 if unknown
+	TimeToZero time.Duration // Projected time until the balance reaches zero, 0 if unknown
+}
+
+type balanceSample struct {
+	at      time.Time
+	balance Decimal
+}
+
+// BalanceWatcher polls GetBalance, tracks the burn rate and notifies when the
+// account availability flips or the balance crosses a threshold.
+type BalanceWatcher struct {
+	Client     *Client
+	Currency   string                   // Optional: currency to watch, defaults to the first one returned
+	Thresholds []Decimal                // Balances that trigger a threshold event when crossed
+	Interval   time.Duration            // Optional: polling interval, defaults to five minutes
+	MaxBackoff time.Duration            // Optional: longest wait between failing polls, defaults to one hour
+	Window     time.Duration            // Optional: period used for the burn rate, defaults to 24 hours
+	OnEvent    func(event BalanceEvent) // Optional: called for every event
+	Events     chan<- BalanceEvent      // Optional: receives every event, blocking until delivered or the context ends
+	OnError    func(err error)          // Optional: called when a poll fails
+
+	mu        sync.Mutex
+	samples   []balanceSample
+	available *bool
+}
+
+// Run polls until ctx is done. Failing polls are retried with exponential
+// backoff.
+func (w *BalanceWatcher) Run(ctx context.Context) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	maxBackoff := w.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = time.Hour
+	}
+
+	wait := interval
+	for {
+		if err := w.Poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if w.OnError != nil {
+				w.OnError(err)
+			}
+			wait = min(wait*2, maxBackoff)
+		} else {
+			wait = interval
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Poll fetches the balance once and emits the resulting events.
+func (w *BalanceWatcher) Poll(ctx context.Context) error {
+	resp, err := w.Client.GetBalance(ctx)
+	if err != nil {
+		return err
+	}
+	info, ok := resp.Balance(w.Currency)
+	if w.Currency == "" && len(resp.BalanceInfos) > 0 {
+		info, ok = resp.BalanceInfos[0], true
+	}
+	if !ok {
+		return errors.New("no balance for currency: " + w.Currency)
+	}
+	total, err := info.Total()
+	if err != nil {
+		return err
+	}
+	for _, event := range w.observe(time.Now(), info.Currency, total, resp.IsAvailable) {
+		if err := w.emit(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// observe records a sample and returns the events it triggers.
+func (w *BalanceWatcher) observe(at time.Time, currency string, balance Decimal, available bool) []BalanceEvent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var previous *balanceSample
+	if len(w.samples) > 0 {
+		last := w.samples[len(w.samples)-1]
+		previous = &last
+	}
+	// A top-up breaks the trend, so the burn rate starts over.
+	if previous != nil && balance.Cmp(previous.balance) > 0 {
+		w.samples = nil
+	}
+	w.samples = append(w.samples, balanceSample{at: at, balance: balance})
+	w.pruneLocked(at)
+
+	rate := w.burnRateLocked()
+	base := BalanceEvent{Time: at, Currency: currency, Balance: balance, Available: available, BurnRate: rate}
+	if rate > 0 && balance.Sign() > 0 {
+		base.TimeToZero = time.Duration(balance.Float64() / rate * float64(time.Hour))
+	}
+
+	var events []BalanceEvent
+	if w.available != nil && *w.available != available {
+		event := base
+		event.Type = BalanceEventAvailability
+		events = append(events, event)
+	}
+	w.available = &available
+
+	for _, threshold := range w.Thresholds {
+		below := balance.Cmp(threshold) < 0
+		wasBelow := previous != nil && previous.balance.Cmp(threshold) < 0
+		if below == wasBelow {
+			continue
+		}
+		event := base
+		event.Type = BalanceEventThreshold
+		event.Threshold = threshold
+		event.Below = below
+		events = append(events, event)
+	}
+	return events
+}
+
+func (w *BalanceWatcher) emit(ctx context.Context, event BalanceEvent) error {
+	if w.OnEvent != nil {
+		w.OnEvent(event)
+	}
+	if w.Events != nil {
+		select {
+		case w.Events <- event:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (w *BalanceWatcher) pruneLocked(now time.Time) {
+	window := w.Window
+	if window <= 0 {
+		window = 24 * time.Hour
+	}
+	i := 0
+	for i < len(w.samples)-1 && now.Sub(w.samples[i].at) > window {
+		i++
+	}
+	w.samples = w.samples[i:]
+}
+
+// burnRateLocked fits a least squares line through the samples and returns the
+// spend per hour, or 0 when the balance is not decreasing.
+func (w *BalanceWatcher) burnRateLocked() float64 {
+	if len(w.samples) < 2 {
+		return 0
+	}
+	origin := w.samples[0].at
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range w.samples {
+		x := s.at.Sub(origin).Hours()
+		y := s.balance.Float64()
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	n := float64(len(w.samples))
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return 0
+	}
+	slope := (n*sumXY - sumX*sumY) / denom
+	if slope >= 0 {
+		return 0
+	}
+	return -slope
+}
+
+// BurnRate returns the current spend per hour, or 0 when unknown.
+func (w *BalanceWatcher) BurnRate() float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.burnRateLocked()
+}
+
+// TimeToZero projects how long the balance lasts at the current burn rate. The
+// second result is false when the balance is not decreasing.
+func (w *BalanceWatcher) TimeToZero() (time.Duration, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	rate := w.burnRateLocked()
+	if rate <= 0 || len(w.samples) == 0 {
+		return 0, false
+	}
+	balance := w.samples[len(w.samples)-1].balance.Float64()
+	if balance <= 0 {
+		return 0, true
+	}
+	return time.Duration(balance / rate * float64(time.Hour)), true
+}
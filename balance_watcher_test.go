@@ -0,0 +1,151 @@
+package deepseek
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBalanceWatcherObserve(t *testing.T) {
+	w := &BalanceWatcher{Thresholds: []Decimal{MustParseDecimal("5")}}
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	if events := w.observe(start, "CNY", MustParseDecimal("10.00"), true); len(events) != 0 {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	w.observe(start.Add(time.Hour), "CNY", MustParseDecimal("8.00"), true)
+	events := w.observe(start.Add(3*time.Hour), "CNY", MustParseDecimal("4.00"), false)
+	if len(events) != 2 || events[0].Type != BalanceEventAvailability || events[1].Type != BalanceEventThreshold || !events[1].Below {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	if rate := w.BurnRate(); rate < 1.9 || rate > 2.1 {
+		t.Fatalf("burn rate = %v, want about 2 per hour", rate)
+	}
+	if ttz, ok := w.TimeToZero(); !ok || ttz < 115*time.Minute || ttz > 125*time.Minute {
+		t.Fatalf("time to zero = %v", ttz)
+	}
+
+	events = w.observe(start.Add(4*time.Hour), "CNY", MustParseDecimal("50.00"), true)
+	if len(events) != 2 || events[1].Below || w.BurnRate() != 0 {
+		t.Fatalf("top-up not handled: %+v", events)
+	}
+}
+
+func TestBalanceWatcherRun(t *testing.T) {
+	replies := []string{
+		"", "", "",
+		`{"is_available":true,"balance_infos":[{"currency":"CNY","total_balance":"10.00"}]}`,
+		`{"is_available":false,"balance_infos":[{"currency":"CNY","total_balance":"4.00"}]}`,
+	}
+	var mu sync.Mutex
+	var polls []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != BalanceSuffix {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		mu.Lock()
+		polls = append(polls, time.Now())
+		n := len(polls)
+		mu.Unlock()
+		if n > len(replies) {
+			n = len(replies)
+		}
+		if replies[n-1] == "" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(replies[n-1]))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan BalanceEvent, 10)
+	var callbacks []BalanceEvent
+	var errs int
+	w := &BalanceWatcher{
+		Client:     &Client{BaseUrl: server.URL},
+		Thresholds: []Decimal{MustParseDecimal("5.00")},
+		Interval:   10 * time.Millisecond,
+		MaxBackoff: 60 * time.Millisecond,
+		OnError:    func(error) { errs++ },
+		OnEvent:    func(event BalanceEvent) { callbacks = append(callbacks, event) },
+		Events:     events,
+	}
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	var received []BalanceEvent
+	for len(received) < 2 {
+		select {
+		case event := <-events:
+			received = append(received, event)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("events = %+v", received)
+		}
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run returned %v", err)
+	}
+
+	if errs != 3 {
+		t.Fatalf("OnError called %d times, want 3", errs)
+	}
+	if received[0].Type != BalanceEventAvailability || received[0].Available ||
+		received[1].Type != BalanceEventThreshold || !received[1].Below || received[1].Balance.String() != "4.00" {
+		t.Fatalf("events = %+v", received)
+	}
+	if len(callbacks) != 2 || callbacks[0].Type != received[0].Type || callbacks[1].Type != received[1].Type {
+		t.Fatalf("OnEvent got %+v", callbacks)
+	}
+
+	// The wait doubles from Interval after each failure, is capped at
+	// MaxBackoff and returns to Interval after a success.
+	mu.Lock()
+	defer mu.Unlock()
+	for i, want := range []time.Duration{20, 40, 60} {
+		if gap := polls[i+1].Sub(polls[i]); gap < want*time.Millisecond {
+			t.Errorf("wait after failure %d = %s, want at least %dms", i+1, gap, want)
+		}
+	}
+	if gap := polls[4].Sub(polls[3]); gap >= 60*time.Millisecond {
+		t.Errorf("wait after success = %s, want the interval", gap)
+	}
+}
+
+func TestBalanceWatcherPoll(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"is_available":true,"balance_infos":[{"currency":"CNY","total_balance":"1.00"},{"currency":"USD","total_balance":"2.00"}]}`))
+	}))
+	defer server.Close()
+	client := &Client{BaseUrl: server.URL}
+
+	// Without a currency the first one is watched.
+	var got []BalanceEvent
+	w := &BalanceWatcher{Client: client, Thresholds: []Decimal{MustParseDecimal("5")}, OnEvent: func(e BalanceEvent) { got = append(got, e) }}
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Currency != "CNY" || got[0].Balance.String() != "1.00" {
+		t.Fatalf("events = %+v", got)
+	}
+
+	w = &BalanceWatcher{Client: client, Currency: "EUR"}
+	if err := w.Poll(context.Background()); err == nil || !strings.Contains(err.Error(), "no balance for currency: EUR") {
+		t.Fatalf("err = %v", err)
+	}
+
+	// A blocked Events send gives up when the context ends.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	w = &BalanceWatcher{Client: client, Thresholds: []Decimal{MustParseDecimal("5")}, Events: make(chan BalanceEvent)}
+	if err := w.Poll(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the context error", err)
+	}
+}
@@ -0,0 +1,230 @@
+package deepseek
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded is matched by every *BudgetError.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// BudgetStatus is the state of a BudgetGuard at its last check.
+type BudgetStatus struct {
+	Available bool      // IsAvailable reported by the balance endpoint
+	Currency  string    // Currency of Balance
+	Balance   Decimal   // Total balance at CheckedAt
+	Spent     Decimal   // Cost recorded in the ledger
+	CheckedAt time.Time // Time of the last successful balance refresh
+}
+
+// BudgetError is returned instead of sending a request when the budget of a
+// BudgetGuard is exhausted, and instead of an HTTP 402 response.
+type BudgetError struct {
+	Reason string
+	Status BudgetStatus
+}
+
+func (e *BudgetError) Error() string {
+	return "budget exceeded: " + e.Reason
+}
+
+func (e *BudgetError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// BudgetGuard refuses or warns on new requests when the account balance or a
+// locally tracked spend cap falls below a threshold. Install it with
+// client.Use(guard.Middleware()).
+type BudgetGuard struct {
+	Client          *Client                   // Client used to call GetBalance
+	Currency        string                    // Optional: balance currency to watch, defaults to the first one returned
+	MinBalance      Decimal                   // Requests are refused when the balance is below this amount
+	WarnBalance     Decimal                   // Optional: OnWarn is called when the balance is below this amount
+	SpendCap        Decimal                   // Optional: requests are refused once the ledger cost reaches this amount
+	Ledger          *UsageLedger              // Optional: spend tracking, defaults to Client.Ledger
+	RefreshInterval time.Duration             // Optional: how often the balance is refreshed, defaults to one minute
+	OnWarn          func(status BudgetStatus) // Optional: called at most once per refresh while below WarnBalance
+
+	mu          sync.Mutex
+	status      BudgetStatus
+	checked     bool
+	lastAttempt time.Time // start of the last refresh, successful or not
+	warnedAt    time.Time
+	refreshing  chan struct{} // closed when the refresh in flight completes
+}
+
+// Middleware returns the middleware enforcing the budget.
+func (g *BudgetGuard) Middleware() Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if strings.HasSuffix(req.URL.Path, BalanceSuffix) {
+				return next.Do(req)
+			}
+			if _, err := g.Check(req.Context()); err != nil {
+				return nil, err
+			}
+			resp, err := next.Do(req)
+			if err != nil {
+				return nil, err
+			}
+			if resp.StatusCode == http.StatusPaymentRequired {
+				resp.Body.Close()
+				status := g.exhausted()
+				return nil, &BudgetError{Reason: "insufficient balance", Status: status}
+			}
+			return resp, nil
+		})
+	}
+}
+
+// Check refreshes the balance when it is stale and returns a *BudgetError if
+// the budget is exhausted. Concurrent checks share a single refresh. When the
+// balance cannot be fetched the last known status is used, and requests are
+// allowed until a balance was seen once; a failed refresh is not retried
+// before RefreshInterval has elapsed.
+func (g *BudgetGuard) Check(ctx context.Context) (BudgetStatus, error) {
+	g.refreshIfStale(ctx)
+
+	g.mu.Lock()
+	status, warn, err := g.evaluate()
+	g.mu.Unlock()
+	// OnWarn runs without the lock, so it may call Status or Check.
+	if warn {
+		g.OnWarn(status)
+	}
+	return status, err
+}
+
+// refreshIfStale refreshes the balance when it is stale. A check arriving
+// while a refresh is in flight waits for it instead of starting another.
+func (g *BudgetGuard) refreshIfStale(ctx context.Context) {
+	g.mu.Lock()
+	interval := g.refreshInterval()
+	if time.Since(g.lastAttempt) < interval || g.checked && time.Since(g.status.CheckedAt) < interval {
+		g.mu.Unlock()
+		return
+	}
+	if done := g.refreshing; done != nil {
+		g.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+		return
+	}
+	done := make(chan struct{})
+	g.refreshing = done
+	g.mu.Unlock()
+	defer func() {
+		g.mu.Lock()
+		g.refreshing = nil
+		g.mu.Unlock()
+		close(done)
+	}()
+	_ = g.Refresh(ctx)
+}
+
+// evaluate checks the last status against the limits and reports whether
+// OnWarn is due. The caller holds g.mu.
+func (g *BudgetGuard) evaluate() (BudgetStatus, bool, error) {
+	status := g.status
+	status.Spent = g.spent()
+	if g.SpendCap.Sign() > 0 && status.Spent.Cmp(g.SpendCap) >= 0 {
+		return status, false, &BudgetError{Reason: "spend cap reached", Status: status}
+	}
+	if !g.checked {
+		return status, false, nil
+	}
+	if !status.Available {
+		return status, false, &BudgetError{Reason: "balance is not available", Status: status}
+	}
+	if status.Balance.Cmp(g.MinBalance) < 0 {
+		return status, false, &BudgetError{Reason: "balance below " + g.MinBalance.String(), Status: status}
+	}
+	if g.OnWarn != nil && !g.WarnBalance.IsZero() && status.Balance.Cmp(g.WarnBalance) < 0 && g.warnedAt != status.CheckedAt {
+		g.warnedAt = status.CheckedAt
+		return status, true, nil
+	}
+	return status, false, nil
+}
+
+// Refresh fetches the current balance. It fails without changing the status
+// when Currency is set and the account has no balance in that currency.
+func (g *BudgetGuard) Refresh(ctx context.Context) error {
+	g.mu.Lock()
+	g.lastAttempt = time.Now()
+	g.mu.Unlock()
+
+	resp, err := g.Client.GetBalance(ctx)
+	if err != nil {
+		return err
+	}
+	info, ok := resp.Balance(g.Currency)
+	if g.Currency == "" && len(resp.BalanceInfos) > 0 {
+		info, ok = resp.BalanceInfos[0], true
+	}
+	if !ok && g.Currency != "" {
+		return fmt.Errorf("no %s balance", g.Currency)
+	}
+	var total Decimal
+	if ok {
+		if total, err = info.Total(); err != nil {
+			return err
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.status = BudgetStatus{
+		Available: resp.IsAvailable,
+		Currency:  info.Currency,
+		Balance:   total,
+		CheckedAt: time.Now(),
+	}
+	g.checked = true
+	return nil
+}
+
+// Status returns the status of the last check without refreshing it.
+func (g *BudgetGuard) Status() BudgetStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	status := g.status
+	status.Spent = g.spent()
+	return status
+}
+
+// exhausted marks the balance as unavailable after the API answered 402.
+func (g *BudgetGuard) exhausted() BudgetStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.status.Available = false
+	g.status.CheckedAt = time.Now()
+	g.checked = true
+	status := g.status
+	status.Spent = g.spent()
+	return status
+}
+
+func (g *BudgetGuard) spent() Decimal {
+	ledger := g.Ledger
+	if ledger == nil && g.Client != nil {
+		ledger = g.Client.Ledger
+	}
+	if ledger == nil {
+		return Decimal{}
+	}
+	return ledger.TotalCost()
+}
+
+func (g *BudgetGuard) refreshInterval() time.Duration {
+	if g.RefreshInterval > 0 {
+		return g.RefreshInterval
+	}
+	return time.Minute
+}
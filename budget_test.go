@@ -0,0 +1,162 @@
+package deepseek
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseDecimal(t *testing.T) {
+	a := MustParseDecimal("110.10")
+	b := MustParseDecimal("0.2")
+	if got := a.Add(b).String(); got != "110.30" {
+		t.Fatalf("Add = %s", got)
+	}
+	if got := b.Sub(a).String(); got != "-109.90" {
+		t.Fatalf("Sub = %s", got)
+	}
+	if got := MustParseDecimal("-0.05").String(); got != "-0.05" {
+		t.Fatalf("String = %s", got)
+	}
+	for _, s := range []string{"", ".", "1.2.3", "abc", "1e3"} {
+		if _, err := ParseDecimal(s); err == nil {
+			t.Errorf("ParseDecimal(%q) succeeded", s)
+		}
+	}
+}
+
+func TestBudgetGuard(t *testing.T) {
+	balance := "0.50"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case BalanceSuffix:
+			w.Write([]byte(`{"is_available":true,"balance_infos":[{"currency":"CNY","total_balance":"` + balance + `"}]}`))
+		default:
+			w.WriteHeader(http.StatusPaymentRequired)
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{BaseUrl: server.URL}
+	guard := &BudgetGuard{Client: client, Currency: "CNY", MinBalance: MustParseDecimal("1.00")}
+	client.Use(guard.Middleware())
+
+	req := &ChatCompletionRequest{Model: DeepSeekChat}
+	_, err := client.CreateChatCompletion(context.Background(), req)
+	var budgetErr *BudgetError
+	if !errors.As(err, &budgetErr) || budgetErr.Status.Balance.String() != "0.50" {
+		t.Fatalf("expected budget error for low balance, got %v", err)
+	}
+
+	balance = "10.00"
+	if err := guard.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.CreateChatCompletion(context.Background(), req)
+	if !errors.Is(err, ErrBudgetExceeded) || guard.Status().Available {
+		t.Fatalf("expected 402 to surface as budget error, got %v", err)
+	}
+}
+
+func TestBudgetGuardOnWarnMayCallGuard(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"is_available":true,"balance_infos":[{"currency":"CNY","total_balance":"5.00"}]}`))
+	}))
+	defer server.Close()
+
+	var warned BudgetStatus
+	guard := &BudgetGuard{Client: &Client{BaseUrl: server.URL}, WarnBalance: MustParseDecimal("10.00")}
+	guard.OnWarn = func(BudgetStatus) {
+		warned = guard.Status()
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := guard.Check(context.Background())
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Check deadlocked in OnWarn")
+	}
+	if warned.Balance.String() != "5.00" {
+		t.Fatalf("status seen by OnWarn = %+v", warned)
+	}
+}
+
+func TestBudgetGuardSharesRefresh(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		w.Write([]byte(`{"is_available":true,"balance_infos":[{"currency":"CNY","total_balance":"10.00"}]}`))
+	}))
+	defer server.Close()
+
+	guard := &BudgetGuard{Client: &Client{BaseUrl: server.URL}, MinBalance: MustParseDecimal("1.00")}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := guard.Check(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("balance fetched %d times, want 1", n)
+	}
+}
+
+func TestBudgetGuardBacksOffFailedRefresh(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == BalanceSuffix {
+			calls.Add(1)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`))
+	}))
+	defer server.Close()
+
+	client := &Client{BaseUrl: server.URL}
+	guard := &BudgetGuard{Client: client, MinBalance: MustParseDecimal("1.00")}
+	client.Use(guard.Middleware())
+	for i := 0; i < 5; i++ {
+		if _, err := client.CreateChatCompletion(context.Background(), &ChatCompletionRequest{Model: DeepSeekChat}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("balance fetched %d times, want 1", n)
+	}
+}
+
+func TestBudgetGuardMissingCurrency(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"is_available":true,"balance_infos":[{"currency":"CNY","total_balance":"10.00"}]}`))
+	}))
+	defer server.Close()
+
+	guard := &BudgetGuard{Client: &Client{BaseUrl: server.URL}, Currency: "USD", MinBalance: MustParseDecimal("1.00")}
+	if err := guard.Refresh(context.Background()); err == nil {
+		t.Fatal("expected an error for a missing currency")
+	}
+	if _, err := guard.Check(context.Background()); err != nil {
+		t.Fatalf("Check refused a request without a known balance: %v", err)
+	}
+}
@@ -8,7 +8,7 @@ import (
 	"errors"
 	"io"
 	"net/http"
-	"strconv"
+	"time"
 
 	deepseek "github.com/p9966/go-deepseek/internal"
 )
@@ -17,6 +17,7 @@ const (
 	ChatMessageRoleSystem    = "system"
 	ChatMessageRoleUser      = "user"
 	ChatMessageRoleAssistant = "assistant"
+	ChatMessageRoleTool      = "tool"
 )
 
 const chatCompletionSuffix = "/chat/completions"
@@ -37,8 +38,10 @@ type ChatCompletionRequest struct {
 }
 
 type ChatCompletionMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // Optional: tool calls requested by the assistant
+	ToolCallID string     `json:"tool_call_id,omitempty"` // Optional: ID of the tool call a tool message answers
 }
 
 type ResponseFormat struct {
@@ -70,6 +73,7 @@ type ChatCompletionResponse struct {
 	Choices           []Choice `json:"choices"`            // List of completion choices generated by the model.
 	Usage             Usage    `json:"usage"`              // Token usage statistics.
 	SystemFingerprint string   `json:"system_fingerprint"` // Fingerprint of the system configuration.
+	Cached            bool     `json:"cached,omitempty"`   // Whether the response was served from a client-side cache instead of the API.
 }
 
 type Choice struct {
@@ -105,22 +109,40 @@ type LogProbs struct {
 }
 
 type Usage struct {
-	PromptTokens          int `json:"prompt_tokens"`            // Number of tokens used in the prompt.
-	CompletionTokens      int `json:"completion_tokens"`        // Number of tokens used in the completion.
-	TotalTokens           int `json:"total_tokens"`             // Total number of tokens used.
-	PromptCacheHitTokens  int `json:"prompt_cache_hit_tokens"`  // Number of tokens served from cache.
-	PromptCacheMissTokens int `json:"prompt_cache_miss_tokens"` // Number of tokens not served from cache.
+	PromptTokens            int                      `json:"prompt_tokens"`                       // Number of tokens used in the prompt.
+	CompletionTokens        int                      `json:"completion_tokens"`                   // Number of tokens used in the completion.
+	TotalTokens             int                      `json:"total_tokens"`                        // Total number of tokens used.
+	PromptCacheHitTokens    int                      `json:"prompt_cache_hit_tokens"`             // Number of tokens served from cache.
+	PromptCacheMissTokens   int                      `json:"prompt_cache_miss_tokens"`            // Number of tokens not served from cache.
+	PromptTokensDetails     *PromptTokensDetails     `json:"prompt_tokens_details,omitempty"`     // Breakdown of the prompt tokens, if available.
+	CompletionTokensDetails *CompletionTokensDetails `json:"completion_tokens_details,omitempty"` // Breakdown of the completion tokens, if available.
+}
+
+type PromptTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"` // Number of prompt tokens served from cache.
+}
+
+type CompletionTokensDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens"` // Number of completion tokens spent on reasoning.
 }
 
 func (c *Client) CreateChatCompletion(ctx context.Context, req *ChatCompletionRequest) (*ChatCompletionResponse, error) {
 	if req == nil {
 		return nil, errors.New("request can not be nil")
 	}
+	if c.Trimmer != nil {
+		fitted := *req
+		if err := c.Trimmer.Fit(&fitted); err != nil {
+			return nil, err
+		}
+		req = &fitted
+	}
 
 	request, err := deepseek.NewRequestBuilder(c.AuthToken).SetMethod(http.MethodPost).SetBaseUrl(c.BaseUrl).SetPath(chatCompletionSuffix).SetBody(req).Build(ctx)
 	if err != nil {
 		return nil, err
 	}
+	start := time.Now()
 	resp, err := c.Do(request)
 	if err != nil {
 		return nil, err
@@ -128,7 +150,7 @@ func (c *Client) CreateChatCompletion(ctx context.Context, req *ChatCompletionRe
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("unexpected status code: " + strconv.Itoa(resp.StatusCode))
+		return nil, newAPIError(resp)
 	}
 
 	buf, err := io.ReadAll(resp.Body)
@@ -140,5 +162,6 @@ func (c *Client) CreateChatCompletion(ctx context.Context, req *ChatCompletionRe
 	if err := json.Unmarshal(buf, &result); err != nil {
 		return nil, err
 	}
+	c.recordUsage(ctx, chatCompletionSuffix, req.Model, result.Usage, start)
 	return &result, nil
 }
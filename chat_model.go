@@ -0,0 +1,378 @@
+package deepseek
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Normalized finish reasons of a GenerateResponse.
+const (
+	FinishReasonStop          = "stop"
+	FinishReasonLength        = "length"
+	FinishReasonToolCalls     = "tool_calls"
+	FinishReasonContentFilter = "content_filter"
+)
+
+// Providers understood by NewChatModel.
+const (
+	ProviderDeepSeek = "deepseek"
+	ProviderOpenAI   = "openai" // any OpenAI-compatible gateway, such as DashScope
+	ProviderOllama   = "ollama"
+)
+
+// ChatModel is a chat backend that hides the wire format of its provider, so
+// application code can switch between DeepSeek, OpenAI-compatible gateways and
+// Ollama by configuration.
+type ChatModel interface {
+	// Generate returns the complete reply to req.
+	Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error)
+	// Stream returns the reply to req as it is generated.
+	Stream(ctx context.Context, req *GenerateRequest) (GenerateStream, error)
+}
+
+// GenerateRequest is a provider independent chat request.
+type GenerateRequest struct {
+	Messages    []ChatCompletionMessage
+	Tools       []Tools  // Optional: tools the model may call
+	Temperature float32  // Optional: sampling temperature
+	TopP        float32  // Optional: nucleus sampling parameter
+	MaxTokens   int      // Optional: maximum tokens of the reply
+	Stop        []string // Optional: stop sequences
+}
+
+// GenerateResponse is a provider independent chat reply.
+type GenerateResponse struct {
+	Model            string
+	Message          ChatCompletionMessage // The assistant message, ready to be appended to the history.
+	ReasoningContent string                // Optional: the model's thinking, for reasoning models
+	FinishReason     string                // One of the FinishReason constants, or the provider's own reason
+	Usage            Usage
+	Backend          string // Optional: the Router backend that served the reply
+}
+
+// GenerateChunk is one piece of a streamed reply. Tool calls are assembled
+// by the stream and delivered whole on the chunk carrying the finish reason.
+type GenerateChunk struct {
+	Content          string
+	ReasoningContent string
+	ToolCalls        []ToolCall
+	FinishReason     string
+	Usage            *Usage // Optional: set on the chunk reporting the token usage
+	Backend          string // Optional: the Router backend that served the reply
+}
+
+// GenerateStream reads a streamed reply. Recv returns io.EOF after the last
+// chunk.
+type GenerateStream interface {
+	Recv() (*GenerateChunk, error)
+	Close() error
+}
+
+// ProviderConfig selects and configures the provider behind a ChatModel.
+type ProviderConfig struct {
+	Provider  string   `json:"provider"`           // One of the Provider constants
+	Model     string   `json:"model"`              // The model name at the provider
+	BaseUrl   string   `json:"base_url,omitempty"` // Optional: defaults to the DeepSeek API, or a local Ollama server; required for ProviderOpenAI
+	AuthToken string   `json:"auth_token,omitempty"`
+	Options   *Options `json:"options,omitempty"` // Optional: Ollama model parameters
+}
+
+// NewChatModel returns the ChatModel described by cfg.
+func NewChatModel(cfg ProviderConfig) (ChatModel, error) {
+	if cfg.Model == "" {
+		return nil, errors.New("model is required")
+	}
+	client := &Client{AuthToken: cfg.AuthToken, BaseUrl: cfg.BaseUrl}
+	switch cfg.Provider {
+	case ProviderDeepSeek, "":
+		if client.BaseUrl == "" {
+			client.BaseUrl = NewClient("").BaseUrl
+		}
+		return &OpenAIChatModel{Client: client, Model: cfg.Model}, nil
+	case ProviderOpenAI:
+		if client.BaseUrl == "" {
+			return nil, errors.New("base url is required for provider " + ProviderOpenAI)
+		}
+		return &OpenAIChatModel{Client: client, Model: cfg.Model}, nil
+	case ProviderOllama:
+		if client.BaseUrl == "" {
+			client.BaseUrl = "http://localhost:11434"
+		}
+		return &OllamaChatModel{Client: client, Model: cfg.Model, Options: cfg.Options}, nil
+	}
+	return nil, fmt.Errorf("unknown provider %q", cfg.Provider)
+}
+
+// OpenAIChatModel is a ChatModel using the OpenAI-style chat endpoint of
+// DeepSeek or a compatible provider.
+type OpenAIChatModel struct {
+	Client *Client
+	Model  string
+}
+
+func (m *OpenAIChatModel) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	if req == nil {
+		return nil, errors.New("request can not be nil")
+	}
+	resp, err := m.Client.CreateChatCompletion(ctx, &ChatCompletionRequest{
+		Model:       m.Model,
+		Messages:    req.Messages,
+		Tools:       req.Tools,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		MaxTokens:   req.MaxTokens,
+		Stop:        req.Stop,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, errors.New("no choices in response")
+	}
+	choice := resp.Choices[0]
+	return &GenerateResponse{
+		Model: resp.Model,
+		Message: ChatCompletionMessage{
+			Role:      ChatMessageRoleAssistant,
+			Content:   choice.Message.Content,
+			ToolCalls: choice.Message.ToolCalls,
+		},
+		ReasoningContent: choice.Message.ReasoningContent,
+		FinishReason:     openAIFinishReason(choice.FinishReason, len(choice.Message.ToolCalls) > 0),
+		Usage:            resp.Usage,
+	}, nil
+}
+
+func (m *OpenAIChatModel) Stream(ctx context.Context, req *GenerateRequest) (GenerateStream, error) {
+	if req == nil {
+		return nil, errors.New("request can not be nil")
+	}
+	stream, err := m.Client.CreateChatCompletionStream(ctx, StreamChatCompletionRequest{
+		Model:         m.Model,
+		Messages:      req.Messages,
+		Tools:         req.Tools,
+		Temperature:   req.Temperature,
+		TopP:          req.TopP,
+		MaxTokens:     req.MaxTokens,
+		Stop:          req.Stop,
+		StreamOptions: &StreamOptions{IncludeUsage: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &openAIGenerateStream{stream: stream}, nil
+}
+
+type openAIGenerateStream struct {
+	stream ChatCompletionStream
+	acc    StreamAccumulator
+}
+
+func (s *openAIGenerateStream) Recv() (*GenerateChunk, error) {
+	resp, err := s.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	s.acc.Add(resp)
+	chunk := &GenerateChunk{Usage: resp.Usage}
+	if len(resp.Choices) > 0 {
+		choice := resp.Choices[0]
+		chunk.Content = choice.Delta.Content
+		chunk.ReasoningContent = choice.Delta.ReasoningContent
+		if choice.FinishReason != "" {
+			chunk.ToolCalls = s.acc.ToolCalls()
+			chunk.FinishReason = openAIFinishReason(choice.FinishReason, len(chunk.ToolCalls) > 0)
+		}
+	}
+	return chunk, nil
+}
+
+func (s *openAIGenerateStream) Close() error {
+	return s.stream.Close()
+}
+
+func openAIFinishReason(reason string, toolCalls bool) string {
+	if reason == "function_call" || (toolCalls && reason == FinishReasonStop) {
+		return FinishReasonToolCalls
+	}
+	return reason
+}
+
+// OllamaChatModel is a ChatModel using the Ollama chat endpoint. Tool calls
+// are given the IDs "call_<index>", and tool messages are matched to their
+// call by ToolCallID.
+type OllamaChatModel struct {
+	Client    *Client
+	Model     string
+	Options   *Options   // Optional: model parameters; the request's sampling fields override them
+	KeepAlive *KeepAlive // Optional: how long the model stays loaded
+	Think     *bool      // Optional: whether thinking models should think
+}
+
+func (m *OllamaChatModel) request(req *GenerateRequest) (*OllamaChatRequest, error) {
+	if req == nil {
+		return nil, errors.New("request can not be nil")
+	}
+	messages, err := ollamaChatMessages(req.Messages)
+	if err != nil {
+		return nil, err
+	}
+	var options Options
+	if m.Options != nil {
+		options = *m.Options
+	}
+	if req.Temperature != 0 {
+		options.Temperature = float64(req.Temperature)
+	}
+	if req.TopP != 0 {
+		options.TopP = float64(req.TopP)
+	}
+	if req.MaxTokens != 0 {
+		options.NumPredict = req.MaxTokens
+	}
+	if len(req.Stop) > 0 {
+		options.Stop = req.Stop
+	}
+	return &OllamaChatRequest{
+		Model:     m.Model,
+		Messages:  messages,
+		Tools:     req.Tools,
+		Think:     m.Think,
+		Options:   &options,
+		KeepAlive: m.KeepAlive,
+	}, nil
+}
+
+func (m *OllamaChatModel) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	ollamaReq, err := m.request(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.Client.CreateOllamaChatCompletion(ctx, ollamaReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Message == nil {
+		return nil, errors.New("no message in response")
+	}
+	calls, err := ollamaToolCalls(resp.Message.ToolCalls, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &GenerateResponse{
+		Model: resp.Model,
+		Message: ChatCompletionMessage{
+			Role:      ChatMessageRoleAssistant,
+			Content:   resp.Message.Content,
+			ToolCalls: calls,
+		},
+		ReasoningContent: resp.Message.Thinking,
+		FinishReason:     ollamaFinishReason(resp.DoneReason, len(calls) > 0),
+		Usage:            ollamaUsage(resp),
+	}, nil
+}
+
+func (m *OllamaChatModel) Stream(ctx context.Context, req *GenerateRequest) (GenerateStream, error) {
+	ollamaReq, err := m.request(req)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := m.Client.CreateOllamaChatCompletionStream(ctx, ollamaReq)
+	if err != nil {
+		return nil, err
+	}
+	return &ollamaGenerateChatStream{stream: stream}, nil
+}
+
+type ollamaGenerateChatStream struct {
+	stream OllamaChatStream
+	calls  []ToolCall
+	done   bool
+}
+
+func (s *ollamaGenerateChatStream) Recv() (*GenerateChunk, error) {
+	if s.done {
+		return nil, io.EOF
+	}
+	resp, err := s.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	chunk := &GenerateChunk{Content: resp.Message.Content, ReasoningContent: resp.Message.Thinking}
+	calls, err := ollamaToolCalls(resp.Message.ToolCalls, len(s.calls))
+	if err != nil {
+		return nil, err
+	}
+	s.calls = append(s.calls, calls...)
+	if resp.Done {
+		s.done = true
+		usage := ollamaUsage(resp)
+		chunk.ToolCalls = s.calls
+		chunk.FinishReason = ollamaFinishReason(resp.DoneReason, len(s.calls) > 0)
+		chunk.Usage = &usage
+	}
+	return chunk, nil
+}
+
+func (s *ollamaGenerateChatStream) Close() error {
+	return s.stream.Close()
+}
+
+// ollamaChatMessages converts OpenAI-style messages for Ollama, which names
+// the tool a tool message answers instead of the call ID.
+func ollamaChatMessages(messages []ChatCompletionMessage) ([]OllamaChatMessage, error) {
+	toolNames := make(map[string]string)
+	out := make([]OllamaChatMessage, len(messages))
+	for i, msg := range messages {
+		out[i] = OllamaChatMessage{Role: msg.Role, Content: msg.Content}
+		for _, tc := range msg.ToolCalls {
+			call, err := OllamaToolFromToolCall(tc)
+			if err != nil {
+				return nil, err
+			}
+			out[i].ToolCalls = append(out[i].ToolCalls, call)
+			toolNames[tc.Id] = tc.Function.Name
+		}
+		if msg.Role == ChatMessageRoleTool {
+			out[i].ToolName = toolNames[msg.ToolCallID]
+		}
+	}
+	return out, nil
+}
+
+func ollamaToolCalls(calls []OllamaTool, offset int) ([]ToolCall, error) {
+	var out []ToolCall
+	for i, call := range calls {
+		tc, err := call.ToolCall(offset + i)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, tc)
+	}
+	return out, nil
+}
+
+func ollamaFinishReason(reason string, toolCalls bool) string {
+	switch {
+	case toolCalls:
+		return FinishReasonToolCalls
+	case reason == FinishReasonLength:
+		return FinishReasonLength
+	}
+	return FinishReasonStop
+}
+
+func ollamaUsage(resp *OllamaChatResponse) Usage {
+	return ollamaCountUsage(resp.PromptEvalCount, resp.EvalCount)
+}
+
+// ollamaCountUsage converts the token counts Ollama reports.
+func ollamaCountUsage(promptEvalCount, evalCount int) Usage {
+	return Usage{
+		PromptTokens:     promptEvalCount,
+		CompletionTokens: evalCount,
+		TotalTokens:      promptEvalCount + evalCount,
+	}
+}
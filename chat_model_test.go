@@ -0,0 +1,127 @@
+package deepseek
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChatModelsNormalizeReplies(t *testing.T) {
+	var ollamaReq OllamaChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case chatCompletionSuffix:
+			w.Write([]byte(`{"model":"deepseek-chat","choices":[{"message":{"role":"assistant","content":"",
+				"tool_calls":[{"index":0,"id":"call_a","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]},
+				"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`))
+		case ollamaChatCompletionSuffix:
+			json.NewDecoder(r.Body).Decode(&ollamaReq)
+			w.Write([]byte(`{"model":"qwen3:8b","message":{"role":"assistant","content":"",
+				"tool_calls":[{"function":{"name":"get_weather","arguments":{"city":"Paris"}}}]},
+				"done":true,"done_reason":"stop","prompt_eval_count":10,"eval_count":5}`))
+		}
+	}))
+	defer server.Close()
+
+	messages := []ChatCompletionMessage{
+		{Role: ChatMessageRoleUser, Content: "Weather in Paris?"},
+		{Role: ChatMessageRoleAssistant, ToolCalls: []ToolCall{{Id: "call_x", Type: "function", Function: FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}}}},
+		{Role: ChatMessageRoleTool, ToolCallID: "call_x", Content: "sunny"},
+	}
+	for _, provider := range []string{ProviderDeepSeek, ProviderOllama} {
+		model, err := NewChatModel(ProviderConfig{Provider: provider, Model: "m", BaseUrl: server.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := model.Generate(context.Background(), &GenerateRequest{Messages: messages, MaxTokens: 100})
+		if err != nil {
+			t.Fatalf("%s: %v", provider, err)
+		}
+		if resp.FinishReason != FinishReasonToolCalls || len(resp.Message.ToolCalls) != 1 {
+			t.Fatalf("%s: reply = %+v", provider, resp)
+		}
+		if args := resp.Message.ToolCalls[0].Function.Arguments; args != `{"city":"Paris"}` {
+			t.Fatalf("%s: arguments = %s", provider, args)
+		}
+		if resp.Usage.TotalTokens != 15 {
+			t.Fatalf("%s: usage = %+v", provider, resp.Usage)
+		}
+	}
+
+	if ollamaReq.Messages[2].ToolName != "get_weather" || ollamaReq.Messages[1].ToolCalls[0].Function.Arguments["city"] != "Paris" {
+		t.Fatalf("ollama messages = %+v", ollamaReq.Messages)
+	}
+	if ollamaReq.Options == nil || ollamaReq.Options.NumPredict != 100 {
+		t.Fatalf("ollama options = %+v", ollamaReq.Options)
+	}
+}
+
+func TestChatModelStreams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case chatCompletionSuffix:
+			var req StreamChatCompletionRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n"))
+			w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"lo\"},\"finish_reason\":\"length\"}]}\n\n"))
+			// Like DeepSeek, usage is only sent when asked for.
+			if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+				w.Write([]byte("data: {\"choices\":[],\"usage\":{\"total_tokens\":7}}\n\n"))
+			}
+			w.Write([]byte("data: [DONE]\n\n"))
+		case ollamaChatCompletionSuffix:
+			w.Write([]byte(`{"message":{"role":"assistant","content":"<think>hm</think>Hel"},"done":false}` + "\n"))
+			w.Write([]byte(`{"message":{"role":"assistant","content":"lo"},"done":true,"done_reason":"length","prompt_eval_count":4,"eval_count":3}` + "\n"))
+		}
+	}))
+	defer server.Close()
+
+	models := map[string]ChatModel{
+		"openai": &OpenAIChatModel{Client: &Client{BaseUrl: server.URL}, Model: "m"},
+		"ollama": &OllamaChatModel{Client: &Client{BaseUrl: server.URL}, Model: "m"},
+	}
+	for name, model := range models {
+		stream, err := model.Stream(context.Background(), &GenerateRequest{Messages: []ChatCompletionMessage{{Role: ChatMessageRoleUser, Content: "hi"}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var content strings.Builder
+		var finishReason string
+		var usage *Usage
+		for {
+			chunk, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			content.WriteString(chunk.Content)
+			if chunk.FinishReason != "" {
+				finishReason = chunk.FinishReason
+			}
+			if chunk.Usage != nil {
+				usage = chunk.Usage
+			}
+		}
+		stream.Close()
+		if content.String() != "Hello" || finishReason != FinishReasonLength || usage == nil || usage.TotalTokens != 7 {
+			t.Fatalf("%s: content = %q, finish reason = %q, usage = %+v", name, content.String(), finishReason, usage)
+		}
+	}
+}
+
+func TestNewChatModelValidatesConfig(t *testing.T) {
+	if _, err := NewChatModel(ProviderConfig{Provider: ProviderOpenAI, Model: "qwen-plus"}); err == nil {
+		t.Fatal("expected an error without a base url")
+	}
+	if _, err := NewChatModel(ProviderConfig{Provider: "bedrock", Model: "m"}); err == nil {
+		t.Fatal("expected an error for an unknown provider")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	deepseek "github.com/p9966/go-deepseek/internal"
 )
@@ -28,6 +29,15 @@ type StreamChatCompletionRequest struct {
 	LogProbs         bool                    `json:"logprobs,omitempty"`
 	TopLogProbs      int                     `json:"top_logprobs,omitempty"`
 	EnableThink      bool                    `json:"enable_thinking"`
+	StreamOptions    *StreamOptions          `json:"stream_options,omitempty"`
+}
+
+// StreamOptions configures a streamed chat completion.
+type StreamOptions struct {
+	// IncludeUsage asks for a final chunk reporting the token usage, which
+	// has no choices. A Client records a streamed call in its Ledger only
+	// when the server sends usage.
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type StreamChatCompletionResponse struct {
@@ -36,6 +46,7 @@ type StreamChatCompletionResponse struct {
 	Created           int64               `json:"created"`
 	Model             string              `json:"model"`
 	Choices           []StreamChatChoices `json:"choices"`
+	Usage             *Usage              `json:"usage,omitempty"`
 	SystemFingerprint string              `json:"system_fingerprint"`
 }
 
@@ -62,6 +73,9 @@ type chatCompletionStream struct {
 	cancel context.CancelFunc
 	resp   *http.Response
 	reader *bufio.Reader
+	client *Client
+	model  string
+	start  time.Time
 }
 
 func (s *chatCompletionStream) Recv() (*StreamChatCompletionResponse, error) {
@@ -86,6 +100,9 @@ func (s *chatCompletionStream) Recv() (*StreamChatCompletionResponse, error) {
 		if err := json.Unmarshal([]byte(line[6:]), &resp); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 		}
+		if resp.Usage != nil && s.client != nil {
+			s.client.recordUsage(s.ctx, chatCompletionSuffix, s.model, *resp.Usage, s.start)
+		}
 
 		return &resp, nil
 	}
@@ -98,20 +115,27 @@ func (s *chatCompletionStream) Close() error {
 
 func (c *Client) CreateChatCompletionStream(ctx context.Context, req StreamChatCompletionRequest) (ChatCompletionStream, error) {
 	req.Stream = true
+	if c.Trimmer != nil {
+		msgs, err := c.Trimmer.Trim(req.Model, req.Messages, req.Tools, req.MaxTokens)
+		if err != nil {
+			return nil, err
+		}
+		req.Messages = msgs
+	}
 	request, err := deepseek.NewRequestBuilder(c.AuthToken).SetBaseUrl(c.BaseUrl).SetPath(chatCompletionSuffix).SetMethod(http.MethodPost).SetBody(req).Build(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build request: %w", err)
 	}
 
+	start := time.Now()
 	resp, err := c.Do(request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+		defer resp.Body.Close()
+		return nil, newAPIError(resp)
 	}
 
 	ctx, cancel := context.WithCancel(ctx)
@@ -120,5 +144,8 @@ func (c *Client) CreateChatCompletionStream(ctx context.Context, req StreamChatC
 		cancel: cancel,
 		resp:   resp,
 		reader: bufio.NewReader(resp.Body),
+		client: c,
+		model:  req.Model,
+		start:  start,
 	}, nil
 }
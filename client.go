@@ -5,12 +5,35 @@ import (
 	"time"
 )
 
+var defaultHTTPClient = &http.Client{
+	Timeout: 120 * time.Second,
+}
+
 type Client struct {
-	AuthToken  string
-	BaseUrl    string
-	httpClient *http.Client
+	AuthToken   string
+	BaseUrl     string
+	Trimmer     *ContextTrimmer // Optional: fits chat histories into the model context window before sending
+	Ledger      *UsageLedger    // Optional: records the usage and cost of calls, see UsageLedger for what is recorded
+	Hedge       *HedgePolicy    // Optional: sends a duplicate of slow requests and keeps the first response
+	httpClient  *http.Client
+	middlewares []Middleware
+}
+
+// Doer sends an HTTP request and returns its response.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc adapts a function to the Doer interface.
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
 }
 
+// Middleware wraps the Doer that sends every request of a Client.
+type Middleware func(next Doer) Doer
+
 // NewClient creates a new DeepSeek client with the provided API key.
 func NewClient(token string) *Client {
 	return &Client{
@@ -19,13 +42,31 @@ func NewClient(token string) *Client {
 	}
 }
 
+// Use appends middlewares to the client. The first middleware added is the
+// outermost one. Use must not be called concurrently with requests.
+func (c *Client) Use(middlewares ...Middleware) {
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
 func (c *Client) Do(req *http.Request) (*http.Response, error) {
-	c.httpClient = &http.Client{
-		Timeout: 120 * time.Second,
+	var doer Doer = defaultHTTPClient
+	if c.httpClient != nil {
+		doer = c.httpClient
+	}
+	if c.Hedge != nil {
+		doer = c.Hedge.wrap(c, doer)
 	}
-	resp, err := c.httpClient.Do(req)
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		doer = c.middlewares[i](doer)
+	}
+	resp, err := doer.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	return resp, nil
 }
+
+// Ptr returns a pointer to v, for optional request fields such as Think.
+func Ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,239 @@
+package deepseek
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// CodeCompletionRequest describes a completion at a cursor position in a file.
+type CodeCompletionRequest struct {
+	Model           string   // Optional: defaults to DeepSeekChat
+	Content         string   // Full contents of the file
+	Cursor          int      // Byte offset of the cursor in Content
+	MaxPromptTokens int      // Optional: token budget for the prefix and suffix windows, defaults to 4096
+	MaxTokens       int      // Optional: maximum tokens to generate, defaults to 256
+	Temperature     float64  // Optional: sampling temperature
+	Stop            []string // Optional: extra stop sequences
+}
+
+// CodeEdit is a completion to insert at the cursor.
+type CodeEdit struct {
+	Offset       int    // Byte offset at which Text is inserted
+	Text         string // Text to insert
+	FinishReason string
+}
+
+// CompleteCode builds a FIM request around the cursor, sends it and returns the
+// cleaned up completion as an edit.
+func (c *Client) CompleteCode(ctx context.Context, req *CodeCompletionRequest) (*CodeEdit, error) {
+	fim, prefix, suffix, err := BuildFIMRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.CreateFINCompletion(ctx, fim)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, errors.New("no completion choices returned")
+	}
+	choice := resp.Choices[0]
+	return &CodeEdit{
+		Offset:       req.Cursor,
+		Text:         CleanCompletion(choice.Text, prefix, suffix),
+		FinishReason: choice.FinishReason,
+	}, nil
+}
+
+// BuildFIMRequest cuts the prefix and suffix windows around the cursor within
+// the token budget and returns the FIM request together with the windows.
+func BuildFIMRequest(req *CodeCompletionRequest) (*FINCompletionRequest, string, string, error) {
+	if req == nil {
+		return nil, "", "", errors.New("request can not be nil")
+	}
+	if req.Cursor < 0 || req.Cursor > len(req.Content) {
+		return nil, "", "", errors.New("cursor out of range")
+	}
+	model := req.Model
+	if model == "" {
+		model = DeepSeekChat
+	}
+	budget := req.MaxPromptTokens
+	if budget <= 0 {
+		budget = 4096
+	}
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = 256
+	}
+
+	counter := NewTokenizer(model)
+	suffixBudget := budget / 4
+	suffix := suffixWindow(req.Content[req.Cursor:], suffixBudget, counter)
+	prefix := prefixWindow(req.Content[:req.Cursor], budget-counter.CountTokens(suffix), counter)
+
+	stop := StopSequences{"\n\n\n"}
+	restOfLine, _, _ := strings.Cut(req.Content[req.Cursor:], "\n")
+	if strings.TrimSpace(restOfLine) != "" {
+		// The cursor is in the middle of a line: complete that line only.
+		stop = StopSequences{"\n"}
+	}
+	stop = append(stop, req.Stop...)
+
+	return &FINCompletionRequest{
+		Model:       model,
+		Prompt:      prefix,
+		Suffix:      suffix,
+		MaxTokens:   maxTokens,
+		Temperature: req.Temperature,
+		Stop:        stop,
+	}, prefix, suffix, nil
+}
+
+// prefixWindow returns the longest run of whole lines ending at the cursor that
+// fits budget, moved forward to the start of a top-level declaration when the
+// window had to be cut.
+func prefixWindow(text string, budget int, counter TokenCounter) string {
+	lines := strings.SplitAfter(text, "\n")
+	used := 0
+	start := len(lines)
+	for i := len(lines) - 1; i >= 0; i-- {
+		n := counter.CountTokens(lines[i])
+		if used+n > budget && start < len(lines) {
+			break
+		}
+		used += n
+		start = i
+	}
+	if start > 0 {
+		// Prefer starting at a function or type boundary in the first half.
+		for i := start; i < start+(len(lines)-start)/2; i++ {
+			if isTopLevelLine(lines[i]) {
+				start = i
+				break
+			}
+		}
+	}
+	return strings.Join(lines[start:], "")
+}
+
+// suffixWindow returns the longest run of whole lines starting at the cursor
+// that fits budget, cut back to end before a top-level declaration when the
+// window had to be cut.
+func suffixWindow(text string, budget int, counter TokenCounter) string {
+	lines := strings.SplitAfter(text, "\n")
+	used := 0
+	end := 0
+	for i, line := range lines {
+		n := counter.CountTokens(line)
+		if used+n > budget && end > 0 {
+			break
+		}
+		used += n
+		end = i + 1
+	}
+	if end < len(lines) {
+		for i := end - 1; i > end/2; i-- {
+			if isTopLevelLine(lines[i]) {
+				end = i
+				break
+			}
+		}
+	}
+	return strings.Join(lines[:end], "")
+}
+
+func isTopLevelLine(line string) bool {
+	if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '\n' {
+		return false
+	}
+	return !strings.HasPrefix(line, "}") && !strings.HasPrefix(line, ")") && !strings.HasPrefix(line, "]")
+}
+
+// CleanCompletion trims the part of a completion that repeats the start of the
+// suffix and cuts it at the first bracket that closes something the prefix did
+// not open.
+func CleanCompletion(text, prefix, suffix string) string {
+	text = balanceBrackets(text, prefix)
+	return trimSuffixOverlap(text, suffix)
+}
+
+// trimSuffixOverlap removes the longest tail of text that equals the beginning
+// of suffix, ignoring leading whitespace of the suffix.
+func trimSuffixOverlap(text, suffix string) string {
+	trimmed := strings.TrimLeft(suffix, " \t\n")
+	for k := min(len(text), len(trimmed)); k > 0; k-- {
+		if strings.HasSuffix(text, trimmed[:k]) {
+			if k < 2 && !strings.ContainsAny(trimmed[:k], ")]}") {
+				break
+			}
+			return strings.TrimRight(text[:len(text)-k], " \t")
+		}
+	}
+	return text
+}
+
+var closingBrackets = map[rune]rune{')': '(', ']': '[', '}': '{'}
+
+func balanceBrackets(text, prefix string) string {
+	stack := openBrackets(prefix)
+	var quote rune
+	escaped := false
+	for i, r := range text {
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\' && quote != '`':
+				escaped = true
+			case r == quote:
+				quote = 0
+			}
+			continue
+		}
+		switch r {
+		case '"', '\'', '`':
+			quote = r
+		case '(', '[', '{':
+			stack = append(stack, r)
+		case ')', ']', '}':
+			if len(stack) == 0 || stack[len(stack)-1] != closingBrackets[r] {
+				return strings.TrimRight(text[:i], " \t")
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return text
+}
+
+// openBrackets returns the brackets left open at the end of text.
+func openBrackets(text string) []rune {
+	var stack []rune
+	var quote rune
+	escaped := false
+	for _, r := range text {
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\' && quote != '`':
+				escaped = true
+			case r == quote, r == '\n' && quote != '`':
+				quote = 0
+			}
+			continue
+		}
+		switch r {
+		case '"', '\'', '`':
+			quote = r
+		case '(', '[', '{':
+			stack = append(stack, r)
+		case ')', ']', '}':
+			if len(stack) > 0 && stack[len(stack)-1] == closingBrackets[r] {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	return stack
+}
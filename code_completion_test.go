@@ -0,0 +1,33 @@
+package deepseek
+
+import "testing"
+
+func TestCleanCompletion(t *testing.T) {
+	tests := []struct {
+		text, prefix, suffix, want string
+	}{
+		{"a + b\n}", "func add(a, b int) int {\n\treturn ", "\n}\n", "a + b\n"},
+		{`x, "y)")) + 1`, "fmt.Println(", ")\n", `x, "y)"`},
+		{"len(items)", "n := ", "\nreturn n\n", "len(items)"},
+	}
+	for _, tt := range tests {
+		if got := CleanCompletion(tt.text, tt.prefix, tt.suffix); got != tt.want {
+			t.Errorf("CleanCompletion(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestBuildFIMRequest(t *testing.T) {
+	content := "package main\n\nfunc main() {\n\tfmt.Println()\n}\n"
+	cursor := len("package main\n\nfunc main() {\n\tfmt.Println(")
+	req, prefix, suffix, err := BuildFIMRequest(&CodeCompletionRequest{Content: content, Cursor: cursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefix+suffix != content || req.Prompt != prefix || req.Suffix != suffix {
+		t.Fatalf("windows do not cover the file: %q + %q", prefix, suffix)
+	}
+	if stop := req.Stop; len(stop) != 1 || stop[0] != "\n" {
+		t.Fatalf("expected single line completion, got stop %q", stop)
+	}
+}
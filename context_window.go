@@ -0,0 +1,126 @@
+package deepseek
+
+import "errors"
+
+// ErrContextTooLong is returned when a request cannot be fitted into the
+// context window of its model even after trimming the history.
+var ErrContextTooLong = errors.New("request exceeds the model context window")
+
+// perMessageTokens approximates the tokens spent on role markers and
+// separators around every message.
+const perMessageTokens = 4
+
+// TokenCounter estimates the number of tokens in a piece of text.
+type TokenCounter interface {
+	CountTokens(text string) int
+}
+
+// TokenCounterFunc adapts a function to the TokenCounter interface.
+type TokenCounterFunc func(text string) int
+
+func (f TokenCounterFunc) CountTokens(text string) int {
+	return f(text)
+}
+
+// ContextTrimmer fits the history of a request into the context window of its
+// model. Leading system messages are always kept; the oldest user turns are
+// dropped first, and a turn is always dropped as a whole so that tool calls
+// are never separated from their results.
+type ContextTrimmer struct {
+	Counter       TokenCounter // Optional: token estimator, defaults to the Tokenizer of the request model
+	ContextWindow int          // Optional: overrides the context window from the model registry
+	ReserveTokens int          // Optional: tokens reserved for the completion when neither the request nor the registry sets them
+}
+
+// Fit trims req.Messages so that the prompt plus the reserved output tokens fit
+// the context window. Requests for models without a known context window are
+// left untouched.
+func (t *ContextTrimmer) Fit(req *ChatCompletionRequest) error {
+	msgs, err := t.Trim(req.Model, req.Messages, req.Tools, req.MaxTokens)
+	if err != nil {
+		return err
+	}
+	req.Messages = msgs
+	return nil
+}
+
+// Trim returns the messages that fit the context window of model, keeping
+// maxTokens free for the completion. The input slice is not modified.
+func (t *ContextTrimmer) Trim(model string, msgs []ChatCompletionMessage, tools []Tools, maxTokens int) ([]ChatCompletionMessage, error) {
+	window, reserve := t.limits(model, maxTokens)
+	if window <= 0 {
+		return msgs, nil
+	}
+	counter := t.counter(model)
+	budget := window - reserve - countToolTokens(counter, tools)
+
+	var system []ChatCompletionMessage
+	rest := msgs
+	for len(rest) > 0 && rest[0].Role == ChatMessageRoleSystem {
+		system = append(system, rest[0])
+		rest = rest[1:]
+	}
+	budget -= countMessageTokens(counter, system)
+
+	turns := splitTurns(rest)
+	used := 0
+	keep := len(turns)
+	for i := len(turns) - 1; i >= 0; i-- {
+		n := countMessageTokens(counter, turns[i])
+		if used+n > budget {
+			break
+		}
+		used += n
+		keep = i
+	}
+	if budget < 0 || (keep == len(turns) && len(turns) > 0) {
+		return nil, ErrContextTooLong
+	}
+	if keep == 0 {
+		return msgs, nil
+	}
+
+	out := make([]ChatCompletionMessage, 0, len(msgs))
+	out = append(out, system...)
+	for _, turn := range turns[keep:] {
+		out = append(out, turn...)
+	}
+	return out, nil
+}
+
+func (t *ContextTrimmer) limits(model string, maxTokens int) (window, reserve int) {
+	info, ok := LookupModel(model)
+	window = t.ContextWindow
+	if window <= 0 && ok {
+		window = info.ContextWindow
+	}
+	switch {
+	case maxTokens > 0:
+		reserve = maxTokens
+	case ok && info.MaxOutputTokens > 0:
+		reserve = info.MaxOutputTokens
+	default:
+		reserve = t.ReserveTokens
+	}
+	return window, reserve
+}
+
+func (t *ContextTrimmer) counter(model string) TokenCounter {
+	if t.Counter != nil {
+		return t.Counter
+	}
+	return NewTokenizer(model)
+}
+
+// splitTurns groups messages into turns that each start at a user message, so
+// assistant tool calls always stay together with their tool results.
+func splitTurns(msgs []ChatCompletionMessage) [][]ChatCompletionMessage {
+	var turns [][]ChatCompletionMessage
+	for i, msg := range msgs {
+		if i == 0 || msg.Role == ChatMessageRoleUser {
+			turns = append(turns, nil)
+		}
+		turns[len(turns)-1] = append(turns[len(turns)-1], msg)
+	}
+	return turns
+}
@@ -0,0 +1,34 @@
+package deepseek
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestContextTrimmerKeepsToolPairs(t *testing.T) {
+	trimmer := &ContextTrimmer{
+		Counter:       TokenCounterFunc(func(text string) int { return len(text) }),
+		ContextWindow: 100,
+		ReserveTokens: 20,
+	}
+	msgs := []ChatCompletionMessage{
+		{Role: ChatMessageRoleSystem, Content: "sys"},
+		{Role: ChatMessageRoleUser, Content: strings.Repeat("a", 30)},
+		{Role: ChatMessageRoleAssistant, ToolCalls: []ToolCall{{Id: "1", Function: FunctionCall{Name: "f", Arguments: "{}"}}}},
+		{Role: ChatMessageRoleTool, ToolCallID: "1", Content: "result"},
+		{Role: ChatMessageRoleUser, Content: strings.Repeat("b", 30)},
+	}
+
+	got, err := trimmer.Trim("unknown", msgs, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Role != ChatMessageRoleSystem || got[1].Content != msgs[4].Content {
+		t.Fatalf("unexpected trimmed history: %+v", got)
+	}
+
+	if _, err := trimmer.Trim("unknown", msgs, nil, 70); !errors.Is(err, ErrContextTooLong) {
+		t.Fatalf("expected ErrContextTooLong, got %v", err)
+	}
+}
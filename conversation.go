@@ -0,0 +1,358 @@
+package deepseek
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrConversationChanged is returned by SummaryMemory.Compact when turns were
+// removed or replaced while the summary was being written. Compacting again
+// starts from the current history.
+var ErrConversationChanged = errors.New("conversation changed during compaction")
+
+// Conversation owns the system prompt and message history of a chat session.
+// It is safe for concurrent use.
+type Conversation struct {
+	mu           sync.Mutex
+	systemPrompt string
+	summary      string
+	history      []ChatCompletionMessage
+	generation   uint64 // incremented whenever messages are removed or replaced
+}
+
+type conversationJSON struct {
+	SystemPrompt string                  `json:"system_prompt,omitempty"`
+	Summary      string                  `json:"summary,omitempty"`
+	Messages     []ChatCompletionMessage `json:"messages"`
+}
+
+// NewConversation creates a conversation with the given system prompt. An empty
+// prompt means no system message is sent.
+func NewConversation(systemPrompt string) *Conversation {
+	return &Conversation{systemPrompt: systemPrompt}
+}
+
+// SystemPrompt returns the system prompt of the conversation.
+func (c *Conversation) SystemPrompt() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.systemPrompt
+}
+
+// SetSystemPrompt replaces the system prompt without touching the history.
+func (c *Conversation) SetSystemPrompt(prompt string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.systemPrompt = prompt
+}
+
+// AddUser appends a user turn.
+func (c *Conversation) AddUser(content string) {
+	c.AddMessage(ChatCompletionMessage{Role: ChatMessageRoleUser, Content: content})
+}
+
+// AddAssistant appends an assistant turn with plain text content.
+func (c *Conversation) AddAssistant(content string) {
+	c.AddMessage(ChatCompletionMessage{Role: ChatMessageRoleAssistant, Content: content})
+}
+
+// AddToolResult appends the result of the tool call identified by toolCallID.
+func (c *Conversation) AddToolResult(toolCallID, content string) {
+	c.AddMessage(ChatCompletionMessage{Role: ChatMessageRoleTool, Content: content, ToolCallID: toolCallID})
+}
+
+// AddMessage appends an arbitrary message to the history.
+func (c *Conversation) AddMessage(msg ChatCompletionMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.history = append(c.history, msg)
+}
+
+// AddResponse appends the first choice of a blocking chat completion as an
+// assistant turn, including any tool calls it requested.
+func (c *Conversation) AddResponse(resp *ChatCompletionResponse) error {
+	if resp == nil || len(resp.Choices) == 0 {
+		return errors.New("response has no choices")
+	}
+	msg := resp.Choices[0].Message
+	c.AddMessage(ChatCompletionMessage{
+		Role:      ChatMessageRoleAssistant,
+		Content:   msg.Content,
+		ToolCalls: msg.ToolCalls,
+	})
+	return nil
+}
+
+// AddStream appends the assistant turn collected by a StreamAccumulator.
+func (c *Conversation) AddStream(acc *StreamAccumulator) {
+	c.AddMessage(acc.Message())
+}
+
+// Messages returns the system prompt, the rolling summary of compacted turns
+// and the history, ready to be sent as the Messages of a request. The returned
+// slice is a copy.
+func (c *Conversation) Messages() []ChatCompletionMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	msgs := make([]ChatCompletionMessage, 0, len(c.history)+2)
+	if c.systemPrompt != "" {
+		msgs = append(msgs, ChatCompletionMessage{Role: ChatMessageRoleSystem, Content: c.systemPrompt})
+	}
+	if c.summary != "" {
+		msgs = append(msgs, ChatCompletionMessage{Role: ChatMessageRoleSystem, Content: summaryNotePrefix + c.summary})
+	}
+	return append(msgs, copyMessages(c.history)...)
+}
+
+// Summary returns the rolling summary of turns removed by a SummaryMemory.
+func (c *Conversation) Summary() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.summary
+}
+
+// snapshot returns copies of the history and summary with the generation they
+// belong to, for a later replaceHead.
+func (c *Conversation) snapshot() ([]ChatCompletionMessage, string, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return copyMessages(c.history), c.summary, c.generation
+}
+
+// replaceHead replaces the first n history messages with summary, provided no
+// message was removed or replaced since the snapshot of generation. Messages
+// appended in the meantime are kept.
+func (c *Conversation) replaceHead(generation uint64, n int, summary string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation || n > len(c.history) {
+		return ErrConversationChanged
+	}
+	c.history = append([]ChatCompletionMessage(nil), c.history[n:]...)
+	c.summary = summary
+	c.generation++
+	return nil
+}
+
+// History returns a copy of the history without the system prompt.
+func (c *Conversation) History() []ChatCompletionMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return copyMessages(c.history)
+}
+
+// Len returns the number of messages in the history.
+func (c *Conversation) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.history)
+}
+
+// Request builds a chat completion request for model from the conversation.
+func (c *Conversation) Request(model string) *ChatCompletionRequest {
+	return &ChatCompletionRequest{Model: model, Messages: c.Messages()}
+}
+
+// StreamRequest builds a streaming chat completion request for model from the
+// conversation.
+func (c *Conversation) StreamRequest(model string) StreamChatCompletionRequest {
+	return StreamChatCompletionRequest{Model: model, Messages: c.Messages()}
+}
+
+// Fork returns an independent copy of the conversation.
+func (c *Conversation) Fork() *Conversation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &Conversation{systemPrompt: c.systemPrompt, summary: c.summary, history: copyMessages(c.history)}
+}
+
+// Branch returns an independent copy of the conversation holding only the
+// first n messages of the history.
+func (c *Conversation) Branch(n int) (*Conversation, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n < 0 || n > len(c.history) {
+		return nil, errors.New("branch point out of range")
+	}
+	return &Conversation{systemPrompt: c.systemPrompt, summary: c.summary, history: copyMessages(c.history[:n])}, nil
+}
+
+// Undo removes the last user turn together with every assistant and tool
+// message that followed it, and returns the removed messages. When the
+// history has no user message, it is left unchanged and Undo returns nil.
+func (c *Conversation) Undo() []ChatCompletionMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cut := -1
+	for i := len(c.history) - 1; i >= 0; i-- {
+		if c.history[i].Role == ChatMessageRoleUser {
+			cut = i
+			break
+		}
+	}
+	if cut < 0 {
+		return nil
+	}
+	removed := copyMessages(c.history[cut:])
+	c.history = c.history[:cut]
+	c.generation++
+	return removed
+}
+
+// Reset clears the history and summary and keeps the system prompt.
+func (c *Conversation) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.history = nil
+	c.summary = ""
+	c.generation++
+}
+
+func (c *Conversation) MarshalJSON() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	history := c.history
+	if history == nil {
+		history = []ChatCompletionMessage{}
+	}
+	return json.Marshal(conversationJSON{SystemPrompt: c.systemPrompt, Summary: c.summary, Messages: history})
+}
+
+func (c *Conversation) UnmarshalJSON(data []byte) error {
+	var v conversationJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.systemPrompt = v.SystemPrompt
+	c.summary = v.Summary
+	c.history = v.Messages
+	c.generation++
+	return nil
+}
+
+// Save writes the conversation to w as JSON.
+func (c *Conversation) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(c)
+}
+
+// LoadConversation reads a conversation previously written with Save.
+func LoadConversation(r io.Reader) (*Conversation, error) {
+	var c Conversation
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func copyMessages(msgs []ChatCompletionMessage) []ChatCompletionMessage {
+	if msgs == nil {
+		return nil
+	}
+	out := make([]ChatCompletionMessage, len(msgs))
+	copy(out, msgs)
+	for i := range out {
+		if out[i].ToolCalls != nil {
+			out[i].ToolCalls = append([]ToolCall(nil), out[i].ToolCalls...)
+		}
+	}
+	return out
+}
+
+// StreamAccumulator collects the chunks of a streamed chat completion into a
+// single assistant message. Feed it every response returned by Recv.
+type StreamAccumulator struct {
+	content      strings.Builder
+	reasoning    strings.Builder
+	toolCalls    map[int]*ToolCall
+	finishReason string
+	usage        *Usage
+}
+
+// Add merges one streamed chunk into the accumulator.
+func (a *StreamAccumulator) Add(resp *StreamChatCompletionResponse) {
+	if resp == nil {
+		return
+	}
+	if resp.Usage != nil {
+		a.usage = resp.Usage
+	}
+	if len(resp.Choices) == 0 {
+		return
+	}
+	choice := resp.Choices[0]
+	a.content.WriteString(choice.Delta.Content)
+	a.reasoning.WriteString(choice.Delta.ReasoningContent)
+	for _, delta := range choice.Delta.ToolCalls {
+		if a.toolCalls == nil {
+			a.toolCalls = make(map[int]*ToolCall)
+		}
+		tc, ok := a.toolCalls[delta.Index]
+		if !ok {
+			tc = &ToolCall{Index: delta.Index}
+			a.toolCalls[delta.Index] = tc
+		}
+		if delta.Id != "" {
+			tc.Id = delta.Id
+		}
+		if delta.Type != "" {
+			tc.Type = delta.Type
+		}
+		if delta.Function.Name != "" {
+			tc.Function.Name = delta.Function.Name
+		}
+		tc.Function.Arguments += delta.Function.Arguments
+	}
+	if choice.FinishReason != "" {
+		a.finishReason = choice.FinishReason
+	}
+}
+
+// Content returns the text streamed so far.
+func (a *StreamAccumulator) Content() string {
+	return a.content.String()
+}
+
+// ReasoningContent returns the reasoning text streamed so far.
+func (a *StreamAccumulator) ReasoningContent() string {
+	return a.reasoning.String()
+}
+
+// FinishReason returns the finish reason of the stream, if it has been received.
+func (a *StreamAccumulator) FinishReason() string {
+	return a.finishReason
+}
+
+// Usage returns the token usage reported by the stream, if any.
+func (a *StreamAccumulator) Usage() *Usage {
+	return a.usage
+}
+
+// ToolCalls returns the tool calls assembled from the streamed fragments,
+// ordered by index.
+func (a *StreamAccumulator) ToolCalls() []ToolCall {
+	if len(a.toolCalls) == 0 {
+		return nil
+	}
+	calls := make([]ToolCall, 0, len(a.toolCalls))
+	for _, tc := range a.toolCalls {
+		calls = append(calls, *tc)
+	}
+	sort.Slice(calls, func(i, j int) bool { return calls[i].Index < calls[j].Index })
+	return calls
+}
+
+// Message returns the accumulated assistant message. Reasoning content is not
+// included, since it must not be sent back to the model.
+func (a *StreamAccumulator) Message() ChatCompletionMessage {
+	return ChatCompletionMessage{
+		Role:      ChatMessageRoleAssistant,
+		Content:   a.Content(),
+		ToolCalls: a.ToolCalls(),
+	}
+}
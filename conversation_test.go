@@ -0,0 +1,60 @@
+package deepseek
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestConversationStreamAndUndo(t *testing.T) {
+	conv := NewConversation("You are a helpful assistant.")
+	conv.AddUser("What's the weather in Hangzhou?")
+
+	var acc StreamAccumulator
+	chunks := []StreamChatCompletionResponse{
+		{Choices: []StreamChatChoices{{Delta: StreamChatChoiceData{ToolCalls: []ToolCall{{Index: 0, Id: "call_1", Type: "function", Function: FunctionCall{Name: "get_weather"}}}}}}},
+		{Choices: []StreamChatChoices{{Delta: StreamChatChoiceData{ToolCalls: []ToolCall{{Index: 0, Function: FunctionCall{Arguments: `{"location":`}}}}}}},
+		{Choices: []StreamChatChoices{{Delta: StreamChatChoiceData{ToolCalls: []ToolCall{{Index: 0, Function: FunctionCall{Arguments: `"Hangzhou"}`}}}}, FinishReason: "tool_calls"}}},
+	}
+	for i := range chunks {
+		acc.Add(&chunks[i])
+	}
+	conv.AddStream(&acc)
+	conv.AddToolResult("call_1", "24℃")
+
+	msgs := conv.Messages()
+	if len(msgs) != 4 || msgs[0].Role != ChatMessageRoleSystem {
+		t.Fatalf("unexpected messages: %+v", msgs)
+	}
+	call := msgs[2].ToolCalls[0]
+	if call.Id != "call_1" || call.Function.Arguments != `{"location":"Hangzhou"}` {
+		t.Fatalf("tool call not assembled: %+v", call)
+	}
+
+	fork := conv.Fork()
+	if removed := conv.Undo(); len(removed) != 3 {
+		t.Fatalf("expected 3 removed messages, got %d", len(removed))
+	}
+	if conv.Len() != 0 || fork.Len() != 3 {
+		t.Fatalf("undo affected fork: conv=%d fork=%d", conv.Len(), fork.Len())
+	}
+
+	var buf bytes.Buffer
+	if err := fork.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadConversation(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.SystemPrompt() != fork.SystemPrompt() || loaded.Len() != 3 || loaded.History()[2].ToolCallID != "call_1" {
+		t.Fatalf("round trip mismatch: %+v", loaded.History())
+	}
+}
+
+func TestConversationUndoWithoutUserTurn(t *testing.T) {
+	conv := NewConversation("")
+	conv.AddAssistant("Hello! How can I help?")
+	if removed := conv.Undo(); removed != nil || conv.Len() != 1 {
+		t.Fatalf("removed = %+v, history = %d messages", removed, conv.Len())
+	}
+}
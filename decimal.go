@@ -0,0 +1,171 @@
+package deepseek
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// Decimal is an exact decimal number, used for monetary amounts returned by
+// the API. The zero value is 0.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int // Number of digits after the decimal point
+}
+
+// ParseDecimal parses a decimal string such as "110.00" or "-0.5" without
+// going through floating point.
+func ParseDecimal(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Decimal{}, errors.New("invalid decimal: empty string")
+	}
+	digits := s
+	if digits[0] == '+' || digits[0] == '-' {
+		digits = digits[1:]
+	}
+	intPart, fracPart, _ := strings.Cut(digits, ".")
+	if intPart == "" && fracPart == "" || !isDigits(intPart) || !isDigits(fracPart) {
+		return Decimal{}, errors.New("invalid decimal: " + s)
+	}
+	unscaled, ok := new(big.Int).SetString(intPart+fracPart, 10)
+	if !ok {
+		return Decimal{}, errors.New("invalid decimal: " + s)
+	}
+	if s[0] == '-' {
+		unscaled.Neg(unscaled)
+	}
+	return Decimal{unscaled: unscaled, scale: len(fracPart)}, nil
+}
+
+// MustParseDecimal is like ParseDecimal but panics on invalid input. It is
+// intended for constants such as thresholds.
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// decimalInt returns n as a Decimal.
+func decimalInt(n int) Decimal {
+	return Decimal{unscaled: big.NewInt(int64(n))}
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (d Decimal) int() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return d.unscaled
+}
+
+// rescale returns the unscaled value of d expressed with the given scale,
+// which must not be smaller than d.scale.
+func (d Decimal) rescale(scale int) *big.Int {
+	factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale-d.scale)), nil)
+	return factor.Mul(factor, d.int())
+}
+
+func align(a, b Decimal) (*big.Int, *big.Int, int) {
+	scale := max(a.scale, b.scale)
+	return a.rescale(scale), b.rescale(scale), scale
+}
+
+// Add returns d + other.
+func (d Decimal) Add(other Decimal) Decimal {
+	x, y, scale := align(d, other)
+	return Decimal{unscaled: x.Add(x, y), scale: scale}
+}
+
+// Sub returns d - other.
+func (d Decimal) Sub(other Decimal) Decimal {
+	x, y, scale := align(d, other)
+	return Decimal{unscaled: x.Sub(x, y), scale: scale}
+}
+
+// Mul returns d * other.
+func (d Decimal) Mul(other Decimal) Decimal {
+	return Decimal{unscaled: new(big.Int).Mul(d.int(), other.int()), scale: d.scale + other.scale}
+}
+
+// shift returns d / 10^n.
+func (d Decimal) shift(n int) Decimal {
+	return Decimal{unscaled: new(big.Int).Set(d.int()), scale: d.scale + n}
+}
+
+// trim returns d without trailing fractional zeros.
+func (d Decimal) trim() Decimal {
+	n, scale := new(big.Int).Set(d.int()), d.scale
+	ten, r := big.NewInt(10), new(big.Int)
+	for scale > 0 {
+		q, m := new(big.Int).QuoRem(n, ten, r)
+		if m.Sign() != 0 {
+			break
+		}
+		n, scale = q, scale-1
+	}
+	return Decimal{unscaled: n, scale: scale}
+}
+
+// Cmp compares d and other and returns -1, 0 or +1.
+func (d Decimal) Cmp(other Decimal) int {
+	x, y, _ := align(d, other)
+	return x.Cmp(y)
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of d.
+func (d Decimal) Sign() int {
+	return d.int().Sign()
+}
+
+// IsZero reports whether d is 0.
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Float64 returns the nearest float64 value of d.
+func (d Decimal) Float64() float64 {
+	f, _ := new(big.Rat).SetFrac(d.int(), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(d.scale)), nil)).Float64()
+	return f
+}
+
+// String formats d with its original number of fractional digits.
+func (d Decimal) String() string {
+	n := d.int()
+	digits := new(big.Int).Abs(n).String()
+	if d.scale > 0 {
+		if len(digits) <= d.scale {
+			digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-d.scale] + "." + digits[len(digits)-d.scale:]
+	}
+	if n.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	v, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
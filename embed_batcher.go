@@ -0,0 +1,390 @@
+package deepseek
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Embedder turns texts into vectors, one per input and in input order.
+type Embedder interface {
+	Embed(ctx context.Context, inputs []string) ([][]float64, error)
+}
+
+// OllamaEmbedder embeds with CreateOllamaEmbed.
+type OllamaEmbedder struct {
+	Client    *Client
+	Model     string
+	Options   *Options   // Optional: model parameters
+	KeepAlive *KeepAlive // Optional: how long the model stays loaded
+}
+
+func (e *OllamaEmbedder) Embed(ctx context.Context, inputs []string) ([][]float64, error) {
+	resp, err := e.Client.CreateOllamaEmbed(ctx, &OllamaEmbedRequest{
+		Model:     e.Model,
+		Input:     inputs,
+		Options:   e.Options,
+		KeepAlive: e.KeepAlive,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Embeddings) != len(inputs) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(resp.Embeddings))
+	}
+	return resp.Embeddings, nil
+}
+
+// OpenAIEmbedder embeds with CreateEmbeddings.
+type OpenAIEmbedder struct {
+	Client     *Client
+	Model      string
+	Dimensions int // Optional: number of dimensions of the output vectors
+}
+
+func (e *OpenAIEmbedder) Embed(ctx context.Context, inputs []string) ([][]float64, error) {
+	resp, err := e.Client.CreateEmbeddings(ctx, &EmbeddingRequest{
+		Model:      e.Model,
+		Input:      inputs,
+		Dimensions: e.Dimensions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Embeddings) != len(inputs) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(resp.Embeddings))
+	}
+	return resp.Embeddings, nil
+}
+
+// EmbeddingBatcher embeds large corpora by splitting them into batches that
+// run with bounded concurrency. Failed batches are retried with backoff unless
+// the API rejected them with a client error other than a rate limit. The
+// output preserves input order, and completed batches can be checkpointed to
+// a file so that a crashed run resumes where it stopped.
+type EmbeddingBatcher struct {
+	Embedder       Embedder
+	BatchSize      int                   // Optional: maximum inputs per batch, defaults to 64
+	MaxBatchTokens int                   // Optional: maximum estimated tokens per batch
+	Counter        TokenCounter          // Optional: token estimator for MaxBatchTokens, defaults to the DeepSeek Tokenizer
+	Concurrency    int                   // Optional: batches in flight, defaults to 4
+	MaxRetries     int                   // Optional: retries per batch, defaults to 3
+	RetryDelay     time.Duration         // Optional: initial backoff between retries, defaults to one second
+	CheckpointPath string                // Optional: file recording completed batches, removed once all inputs are embedded
+	OnProgress     func(done, total int) // Optional: called after every completed batch with the number of inputs embedded
+}
+
+type embedBatch struct {
+	index      int
+	start, end int
+}
+
+type checkpointHeader struct {
+	Fingerprint string `json:"fingerprint"`
+}
+
+type checkpointRecord struct {
+	Batch   int         `json:"batch"`
+	Vectors [][]float64 `json:"vectors"`
+}
+
+// Embed returns one vector per input, in input order.
+func (b *EmbeddingBatcher) Embed(ctx context.Context, inputs []string) ([][]float64, error) {
+	if b.Embedder == nil {
+		return nil, errors.New("batcher has no embedder")
+	}
+	batches := b.split(inputs)
+	out := make([][]float64, len(inputs))
+
+	done := make(map[int]bool)
+	var checkpoint *os.File
+	if b.CheckpointPath != "" {
+		var err error
+		checkpoint, err = b.openCheckpoint(fingerprint(embedderID(b.Embedder), inputs, batches), batches, out, done)
+		if err != nil {
+			return nil, err
+		}
+		defer checkpoint.Close()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		embedded int
+	)
+	for _, batch := range batches {
+		if done[batch.index] {
+			embedded += batch.end - batch.start
+		}
+	}
+	// OnProgress runs on its own goroutine, in order, so a slow callback
+	// does not hold up the workers and may call back into the batcher.
+	var progress chan int
+	reported := make(chan struct{})
+	if b.OnProgress != nil {
+		progress = make(chan int, len(batches)+1)
+		go func() {
+			defer close(reported)
+			for n := range progress {
+				b.OnProgress(n, len(inputs))
+			}
+		}()
+		if embedded > 0 {
+			progress <- embedded
+		}
+	} else {
+		close(reported)
+	}
+
+	concurrency := b.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	sem := make(chan struct{}, concurrency)
+	for _, batch := range batches {
+		if done[batch.index] {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(batch embedBatch) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			vectors, err := b.embedWithRetry(ctx, inputs[batch.start:batch.end])
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil && checkpoint != nil {
+				err = appendCheckpoint(checkpoint, checkpointRecord{Batch: batch.index, Vectors: vectors})
+			}
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("batch %d: %w", batch.index, err)
+					cancel()
+				}
+				return
+			}
+			copy(out[batch.start:batch.end], vectors)
+			embedded += batch.end - batch.start
+			if progress != nil {
+				progress <- embedded
+			}
+		}(batch)
+	}
+	wg.Wait()
+	if progress != nil {
+		close(progress)
+	}
+	<-reported
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if checkpoint != nil {
+		checkpoint.Close()
+		os.Remove(b.CheckpointPath)
+	}
+	return out, nil
+}
+
+// split cuts inputs into batches bounded by BatchSize and MaxBatchTokens. An
+// input larger than MaxBatchTokens gets a batch of its own.
+func (b *EmbeddingBatcher) split(inputs []string) []embedBatch {
+	size := b.BatchSize
+	if size <= 0 {
+		size = 64
+	}
+	counter := b.Counter
+	if counter == nil {
+		counter = NewTokenizer(DeepSeekChat)
+	}
+
+	var batches []embedBatch
+	start, tokens := 0, 0
+	for i, input := range inputs {
+		n := 0
+		if b.MaxBatchTokens > 0 {
+			n = counter.CountTokens(input)
+		}
+		full := i-start >= size || (b.MaxBatchTokens > 0 && tokens+n > b.MaxBatchTokens)
+		if full && i > start {
+			batches = append(batches, embedBatch{index: len(batches), start: start, end: i})
+			start, tokens = i, 0
+		}
+		tokens += n
+	}
+	if start < len(inputs) {
+		batches = append(batches, embedBatch{index: len(batches), start: start, end: len(inputs)})
+	}
+	return batches
+}
+
+func (b *EmbeddingBatcher) embedWithRetry(ctx context.Context, inputs []string) ([][]float64, error) {
+	retries := b.MaxRetries
+	if retries <= 0 {
+		retries = 3
+	}
+	delay := b.RetryDelay
+	if delay <= 0 {
+		delay = time.Second
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		var vectors [][]float64
+		vectors, err = b.Embedder.Embed(ctx, inputs)
+		if err == nil {
+			if len(vectors) != len(inputs) {
+				err = fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(vectors))
+			} else {
+				return vectors, nil
+			}
+		}
+		if attempt >= retries || ctx.Err() != nil || permanentError(err) {
+			return nil, err
+		}
+		timer := time.NewTimer(delay << attempt)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// permanentError reports whether err is a client error such as a bad key or
+// an unknown model, which a retry cannot fix. Rate limits are retried.
+func permanentError(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode >= 400 && apiErr.StatusCode < 500 &&
+		apiErr.StatusCode != http.StatusTooManyRequests
+}
+
+// openCheckpoint loads the batches completed by an earlier run into out and
+// done, and returns the checkpoint file opened for appending. A checkpoint
+// written for different inputs or batch sizes is discarded. The file is
+// rewritten with only the records that were read back, so a line torn by a
+// crash does not swallow later appends.
+func (b *EmbeddingBatcher) openCheckpoint(fp string, batches []embedBatch, out [][]float64, done map[int]bool) (*os.File, error) {
+	if f, err := os.Open(b.CheckpointPath); err == nil {
+		if !readCheckpoint(f, fp, batches, out, done) {
+			clear(done)
+		}
+		f.Close()
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(b.CheckpointPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	line, _ := json.Marshal(checkpointHeader{Fingerprint: fp})
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return nil, err
+	}
+	for _, batch := range batches {
+		if !done[batch.index] {
+			continue
+		}
+		if err := appendCheckpoint(f, checkpointRecord{Batch: batch.index, Vectors: out[batch.start:batch.end]}); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func readCheckpoint(f *os.File, fp string, batches []embedBatch, out [][]float64, done map[int]bool) bool {
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 1<<30)
+	if !scanner.Scan() {
+		return false
+	}
+	var header checkpointHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Fingerprint != fp {
+		return false
+	}
+	for scanner.Scan() {
+		var record checkpointRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// A partially written last line from a crash.
+			break
+		}
+		if record.Batch < 0 || record.Batch >= len(batches) {
+			continue
+		}
+		batch := batches[record.Batch]
+		if len(record.Vectors) != batch.end-batch.start {
+			continue
+		}
+		copy(out[batch.start:batch.end], record.Vectors)
+		done[record.Batch] = true
+	}
+	return true
+}
+
+func appendCheckpoint(f *os.File, record checkpointRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// fingerprint identifies a corpus, its batch layout and the embedder, so a
+// checkpoint is not resumed with vectors from another embedding space.
+func fingerprint(embedder string, inputs []string, batches []embedBatch) string {
+	h := sha256.New()
+	h.Write([]byte(embedder))
+	h.Write([]byte{0})
+	for _, input := range inputs {
+		h.Write([]byte(strconv.Itoa(len(input))))
+		h.Write([]byte{0})
+		h.Write([]byte(input))
+	}
+	for _, batch := range batches {
+		h.Write([]byte(strconv.Itoa(batch.end)))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// embedderID identifies the model and output dimensions of an embedder. Other
+// embedders are identified by their type only.
+func embedderID(e Embedder) string {
+	switch e := e.(type) {
+	case *OllamaEmbedder:
+		return fmt.Sprintf("ollama\x00%s\x00%s", e.Client.BaseUrl, e.Model)
+	case *OpenAIEmbedder:
+		return fmt.Sprintf("openai\x00%s\x00%s\x00%d", e.Client.BaseUrl, e.Model, e.Dimensions)
+	}
+	return fmt.Sprintf("%T", e)
+}
@@ -0,0 +1,235 @@
+package deepseek
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeEmbedder embeds each input as its number, failing once per batch when
+// flaky is set and permanently for inputs in broken.
+type fakeEmbedder struct {
+	mu     sync.Mutex
+	flaky  bool
+	broken map[string]bool
+	seen   map[string]int
+	calls  int
+}
+
+func (e *fakeEmbedder) Embed(ctx context.Context, inputs []string) ([][]float64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls++
+	if e.seen == nil {
+		e.seen = make(map[string]int)
+	}
+	e.seen[inputs[0]]++
+	if e.flaky && e.seen[inputs[0]] == 1 {
+		return nil, errors.New("transient")
+	}
+	vectors := make([][]float64, len(inputs))
+	for i, input := range inputs {
+		if e.broken[input] {
+			return nil, errors.New("broken input")
+		}
+		n, _ := strconv.Atoi(input)
+		vectors[i] = []float64{float64(n)}
+	}
+	return vectors, nil
+}
+
+func numberedInputs(n int) []string {
+	inputs := make([]string, n)
+	for i := range inputs {
+		inputs[i] = strconv.Itoa(i)
+	}
+	return inputs
+}
+
+func TestEmbeddingBatcherPreservesOrderAndRetries(t *testing.T) {
+	embedder := &fakeEmbedder{flaky: true}
+	var progress []int
+	batcher := &EmbeddingBatcher{
+		Embedder:    embedder,
+		BatchSize:   7,
+		Concurrency: 3,
+		RetryDelay:  time.Millisecond,
+		OnProgress:  func(done, total int) { progress = append(progress, done) },
+	}
+	inputs := numberedInputs(50)
+	vectors, err := batcher.Embed(context.Background(), inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range vectors {
+		if v[0] != float64(i) {
+			t.Fatalf("vector %d = %v", i, v)
+		}
+	}
+	if embedder.calls != 16 {
+		t.Fatalf("calls = %d, want 16 (8 batches, each retried once)", embedder.calls)
+	}
+	if len(progress) != 8 || progress[len(progress)-1] != 50 {
+		t.Fatalf("progress = %v", progress)
+	}
+}
+
+func TestEmbeddingBatcherSplitsByTokens(t *testing.T) {
+	batcher := &EmbeddingBatcher{
+		BatchSize:      100,
+		MaxBatchTokens: 10,
+		Counter:        TokenCounterFunc(func(s string) int { return len(s) }),
+	}
+	batches := batcher.split([]string{"aaaa", "bbbb", "cccc", "dddddddddddddddd", "e"})
+	want := [][2]int{{0, 2}, {2, 3}, {3, 4}, {4, 5}}
+	if len(batches) != len(want) {
+		t.Fatalf("batches = %v", batches)
+	}
+	for i, b := range batches {
+		if b.start != want[i][0] || b.end != want[i][1] {
+			t.Fatalf("batch %d = [%d, %d), want %v", i, b.start, b.end, want[i])
+		}
+	}
+}
+
+func TestEmbeddingBatcherResumesFromCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "embed.checkpoint")
+	inputs := numberedInputs(20)
+
+	failing := &fakeEmbedder{broken: map[string]bool{"15": true}}
+	batcher := &EmbeddingBatcher{
+		Embedder:       failing,
+		BatchSize:      5,
+		Concurrency:    1,
+		MaxRetries:     1,
+		RetryDelay:     time.Millisecond,
+		CheckpointPath: path,
+	}
+	if _, err := batcher.Embed(context.Background(), inputs); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Simulate a crash in the middle of writing a record.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"batch":3,"vec`)
+	f.Close()
+
+	resumed := &fakeEmbedder{}
+	batcher.Embedder = resumed
+	vectors, err := batcher.Embed(context.Background(), inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range vectors {
+		if v[0] != float64(i) {
+			t.Fatalf("vector %d = %v", i, v)
+		}
+	}
+	if resumed.calls != 1 || resumed.seen["15"] != 1 {
+		t.Fatalf("resumed run embedded %v, want only the failed batch", resumed.seen)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("checkpoint should be removed after success")
+	}
+}
+
+// countingEmbedder closes full once it has embedded n batches.
+type countingEmbedder struct {
+	fakeEmbedder
+	n    int
+	full chan struct{}
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, inputs []string) ([][]float64, error) {
+	vectors, err := e.fakeEmbedder.Embed(ctx, inputs)
+	e.mu.Lock()
+	if e.calls == e.n {
+		close(e.full)
+	}
+	e.mu.Unlock()
+	return vectors, err
+}
+
+func TestEmbeddingBatcherSlowProgressDoesNotStall(t *testing.T) {
+	embedder := &countingEmbedder{n: 4, full: make(chan struct{})}
+	var progress []int
+	nested := false
+	batcher := &EmbeddingBatcher{Embedder: embedder, BatchSize: 5, Concurrency: 1}
+	batcher.OnProgress = func(done, total int) {
+		if !nested {
+			nested = true
+			select {
+			case <-embedder.full:
+			case <-time.After(time.Second):
+				t.Error("workers waited for OnProgress")
+			}
+			// The callback may use the batcher.
+			if _, err := batcher.Embed(context.Background(), numberedInputs(1)); err != nil {
+				t.Error(err)
+			}
+		}
+		progress = append(progress, done)
+	}
+	if _, err := batcher.Embed(context.Background(), numberedInputs(20)); err != nil {
+		t.Fatal(err)
+	}
+	// The nested run reports first, from inside the outer run's first call.
+	if len(progress) != 5 || progress[0] != 1 || progress[1] != 5 || progress[4] != 20 {
+		t.Fatalf("progress = %v", progress)
+	}
+}
+
+func TestCheckpointFingerprintCoversModel(t *testing.T) {
+	inputs := numberedInputs(3)
+	batches := (&EmbeddingBatcher{}).split(inputs)
+	client := &Client{BaseUrl: "http://localhost:11434"}
+	a := fingerprint(embedderID(&OllamaEmbedder{Client: client, Model: "nomic-embed-text"}), inputs, batches)
+	b := fingerprint(embedderID(&OllamaEmbedder{Client: client, Model: "mxbai-embed-large"}), inputs, batches)
+	if a == b {
+		t.Fatal("checkpoints of different models share a fingerprint")
+	}
+}
+
+// rejectingEmbedder fails every call with err.
+type rejectingEmbedder struct {
+	err   error
+	calls atomic.Int32
+}
+
+func (e *rejectingEmbedder) Embed(ctx context.Context, inputs []string) ([][]float64, error) {
+	e.calls.Add(1)
+	return nil, e.err
+}
+
+func TestEmbeddingBatcherDoesNotRetryClientErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		calls  int32
+	}{
+		{http.StatusUnauthorized, 1},
+		{http.StatusNotFound, 1},
+		{http.StatusTooManyRequests, 3},
+	}
+	for _, tt := range tests {
+		embedder := &rejectingEmbedder{err: &APIError{StatusCode: tt.status}}
+		batcher := &EmbeddingBatcher{Embedder: embedder, MaxRetries: 2, RetryDelay: time.Millisecond}
+		_, err := batcher.Embed(context.Background(), numberedInputs(3))
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status {
+			t.Fatalf("status %d: err = %v", tt.status, err)
+		}
+		if n := embedder.calls.Load(); n != tt.calls {
+			t.Errorf("status %d: %d attempts, want %d", tt.status, n, tt.calls)
+		}
+	}
+}
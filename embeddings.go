@@ -0,0 +1,127 @@
+package deepseek
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	deepseek "github.com/p9966/go-deepseek/internal"
+)
+
+const embeddingsSuffix = "/embeddings"
+
+const (
+	EmbeddingEncodingFloat  = "float"
+	EmbeddingEncodingBase64 = "base64"
+)
+
+type EmbeddingRequest struct {
+	Model          string `json:"model"`
+	Input          any    `json:"input"`                     // text or list of text to generate embeddings for
+	Dimensions     int    `json:"dimensions,omitempty"`      // Optional: number of dimensions of the output vectors, for models that support it
+	EncodingFormat string `json:"encoding_format,omitempty"` // Optional: "float" or "base64"; base64 vectors are decoded transparently
+	User           string `json:"user,omitempty"`            // Optional: end-user identifier
+}
+
+type EmbeddingResponse struct {
+	Object     string          `json:"object"`
+	Data       []EmbeddingData `json:"data"`
+	Model      string          `json:"model"`
+	Usage      EmbeddingUsage  `json:"usage"`
+	Embeddings [][]float64     `json:"-"` // Vectors ordered like the inputs, in the same shape as OllamaEmbedResponse.Embeddings
+}
+
+type EmbeddingData struct {
+	Object    string          `json:"object"`
+	Index     int             `json:"index"`
+	Embedding json.RawMessage `json:"embedding"` // A list of floats, or a base64 string of little-endian float32 values
+}
+
+type EmbeddingUsage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+// CreateEmbeddings calls the /embeddings endpoint of OpenAI-compatible
+// providers such as DashScope.
+func (c *Client) CreateEmbeddings(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	if req == nil {
+		return nil, errors.New("request can not be nil")
+	}
+	switch req.Input.(type) {
+	case string, []string:
+	default:
+		return nil, errors.New("input must be a string or a list of strings")
+	}
+
+	request, err := deepseek.NewRequestBuilder(c.AuthToken).SetMethod(http.MethodPost).SetBaseUrl(c.BaseUrl).SetPath(embeddingsSuffix).SetBody(req).Build(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := c.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	var embedResp EmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embedResp); err != nil {
+		return nil, err
+	}
+	if err := embedResp.decodeEmbeddings(); err != nil {
+		return nil, err
+	}
+	c.recordUsage(ctx, embeddingsSuffix, req.Model, Usage{PromptTokens: embedResp.Usage.PromptTokens, TotalTokens: embedResp.Usage.TotalTokens}, start)
+	return &embedResp, nil
+}
+
+func (r *EmbeddingResponse) decodeEmbeddings() error {
+	data := append([]EmbeddingData(nil), r.Data...)
+	sort.Slice(data, func(i, j int) bool { return data[i].Index < data[j].Index })
+	r.Embeddings = make([][]float64, len(data))
+	for i, d := range data {
+		vector, err := decodeEmbedding(d.Embedding)
+		if err != nil {
+			return fmt.Errorf("embedding %d: %w", d.Index, err)
+		}
+		r.Embeddings[i] = vector
+	}
+	return nil
+}
+
+func decodeEmbedding(raw json.RawMessage) ([]float64, error) {
+	var encoded string
+	if err := json.Unmarshal(raw, &encoded); err != nil {
+		var vector []float64
+		if err := json.Unmarshal(raw, &vector); err != nil {
+			return nil, err
+		}
+		return vector, nil
+	}
+
+	buf, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf)%4 != 0 {
+		return nil, errors.New("base64 embedding is not a list of float32 values")
+	}
+	vector := make([]float64, len(buf)/4)
+	for i := range vector {
+		vector[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(buf[i*4:])))
+	}
+	return vector, nil
+}
@@ -0,0 +1,43 @@
+package deepseek
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestCreateEmbeddingsDecodesBase64(t *testing.T) {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint32(buf, math.Float32bits(0.5))
+	binary.LittleEndian.PutUint32(buf[4:], math.Float32bits(-2))
+	encoded := base64.StdEncoding.EncodeToString(buf)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"object":"list","model":"text-embedding-v3","data":[
+			{"object":"embedding","index":1,"embedding":"` + encoded + `"},
+			{"object":"embedding","index":0,"embedding":[1,2]}],
+			"usage":{"prompt_tokens":4,"total_tokens":4}}`))
+	}))
+	defer server.Close()
+
+	client := &Client{BaseUrl: server.URL}
+	resp, err := client.CreateEmbeddings(context.Background(), &EmbeddingRequest{
+		Model: "text-embedding-v3",
+		Input: []string{"a", "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]float64{{1, 2}, {0.5, -2}}; !reflect.DeepEqual(resp.Embeddings, want) {
+		t.Fatalf("embeddings = %v, want %v", resp.Embeddings, want)
+	}
+
+	if _, err := client.CreateEmbeddings(context.Background(), &EmbeddingRequest{Input: 1}); err == nil {
+		t.Fatal("expected error for invalid input")
+	}
+}
@@ -0,0 +1,30 @@
+package deepseek
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// maxErrorBody bounds how much of an error response is kept in an APIError.
+const maxErrorBody = 64 << 10
+
+// APIError is returned when an endpoint answers with a status other than 200.
+type APIError struct {
+	StatusCode int
+	Body       string // The start of the response body, usually a JSON error object.
+}
+
+func (e *APIError) Error() string {
+	msg := "unexpected status code: " + strconv.Itoa(e.StatusCode)
+	if e.Body != "" {
+		msg += ", body: " + e.Body
+	}
+	return msg
+}
+
+// newAPIError reads the error response resp. The caller closes the body.
+func newAPIError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+}
@@ -16,7 +16,7 @@ func main() {
 	client := deepseek.NewClient(os.Getenv("DEEPSEEK_API_KEY"))
 	scanner := bufio.NewScanner(os.Stdin)
 
-	var messages []deepseek.ChatCompletionMessage
+	conversation := deepseek.NewConversation("")
 
 	//  To do console input in debug mode, add "console": "integratedTerminal" to launch.json
 	for {
@@ -34,14 +34,11 @@ func main() {
 			break
 		}
 
-		messages = append(messages, deepseek.ChatCompletionMessage{
-			Role:    deepseek.ChatMessageRoleUser,
-			Content: input,
-		})
+		conversation.AddUser(input)
 
 		request := deepseek.StreamChatCompletionRequest{
 			Model:    deepseek.DeepSeekChat,
-			Messages: messages,
+			Messages: conversation.Messages(),
 		}
 
 		ctx := context.Background()
@@ -49,9 +46,9 @@ func main() {
 		if err != nil {
 			log.Fatalf("ChatCompletionStream failed: %v", err)
 		}
-		defer stream.Close()
 
 		fmt.Print("DeepSeek: ")
+		var acc deepseek.StreamAccumulator
 		for {
 			response, err := stream.Recv()
 			if errors.Is(err, io.EOF) {
@@ -62,14 +59,12 @@ func main() {
 				log.Fatalf("ChatCompletionStream stream.Recv() failed: %v", err)
 			}
 
-			if len(response.Choices) > 0 && response.Choices[0].FinishReason == "" {
-				content := response.Choices[0].Delta.Content
-				messages = append(messages, deepseek.ChatCompletionMessage{
-					Role:    deepseek.ChatMessageRoleAssistant,
-					Content: content,
-				})
-				fmt.Print(content)
+			acc.Add(response)
+			if len(response.Choices) > 0 {
+				fmt.Print(response.Choices[0].Delta.Content)
 			}
 		}
+		stream.Close()
+		conversation.AddStream(&acc)
 	}
 }
@@ -11,13 +11,13 @@ import (
 
 func main() {
 	client := deepseek.NewClient(os.Getenv("DEEPSEEK_API_KEY"))
-	request := deepseek.FINCompletionRequest{
+	request := deepseek.FIMCompletionRequest{
 		Model:  deepseek.DeepSeekChat,
 		Prompt: "What is the weather like today?",
 	}
 
 	ctx := context.Background()
-	resp, err := client.CreateFINCompletion(ctx, &request)
+	resp, err := client.CreateFIMCompletion(ctx, &request)
 	if err != nil {
 		log.Fatalf("Error creating completion: %v", err)
 	}
@@ -63,5 +63,8 @@ func executeChatRequest(client deepseek.Client, request deepseek.OllamaChatReque
 	if err != nil {
 		log.Fatalf("failed to create generate: %v", err)
 	}
+	if resp.Message.Thinking != "" {
+		fmt.Println("Thinking:", resp.Message.Thinking)
+	}
 	fmt.Println(resp.Message.Content)
 }
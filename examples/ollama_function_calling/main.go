@@ -8,10 +8,35 @@ import (
 	"github.com/p9966/go-deepseek"
 )
 
+type weatherArgs struct {
+	Location string `json:"location"`
+}
+
 func main() {
 	client := deepseek.Client{
 		BaseUrl: "http://localhost:11434",
 	}
+
+	// The same registry can serve tool calls from CreateChatCompletion via RunToolCalls.
+	registry := deepseek.NewToolRegistry()
+	err := deepseek.RegisterTool(registry, deepseek.Function{
+		Name:        "get_weather",
+		Description: "Get weather of an location, the user shoud supply a location first",
+		Parameters: &deepseek.Parameters{
+			Type: "object",
+			Properties: map[string]any{
+				"location": map[string]any{
+					"description": "The location to get weather",
+					"type":        "string",
+				},
+			},
+			Required: []string{"location"},
+		},
+	}, getWeather)
+	if err != nil {
+		log.Fatalf("failed to register tool: %v", err)
+	}
+
 	request := deepseek.OllamaChatRequest{
 		Model: deepseek.QWen2_5_7b,
 		Messages: []deepseek.OllamaChatMessage{
@@ -21,43 +46,19 @@ func main() {
 			},
 		},
 		Stream: false,
-		Tools: []deepseek.Tools{
-			{
-				Type: "function",
-				Function: deepseek.Function{
-					Name:        "get_weather",
-					Description: "Get weather of an location, the user shoud supply a location first",
-					Parameters: &deepseek.Parameters{
-						Type: "object",
-						Properties: map[string]any{
-							"location": map[string]any{
-								"description": "The location to get weather",
-								"type":        "string",
-							},
-						},
-						Required: []string{"location"},
-					},
-				},
-			},
-		},
+		Tools:  registry.Tools(),
 	}
 
-	funcMap := map[string]func(...string) string{}
-	funcMap["get_weather"] = getWeather
 	response, err := client.CreateOllamaChatCompletion(context.TODO(), &request)
 	if err != nil {
-		log.Fatalf("failed to create ollama embed: %v", err)
+		log.Fatalf("failed to create ollama chat: %v", err)
 	}
-	if response.Message.ToolCalls != nil {
-		if getWeater, ok := funcMap[response.Message.ToolCalls[0].Function.Name]; ok {
-			result := getWeater(response.Message.ToolCalls[0].Function.Arguments["location"].(string))
-			fmt.Println(result)
-		}
+	for _, result := range registry.RunOllamaToolCalls(context.TODO(), response.Message.ToolCalls) {
+		fmt.Printf("%s: %s\n", result.ToolName, result.Content)
 	}
 }
 
 // functions
-func getWeather(args ...string) string {
-	location := args[0]
-	return fmt.Sprintf("The weather in %s is sunny", location)
+func getWeather(ctx context.Context, args weatherArgs) (string, error) {
+	return fmt.Sprintf("The weather in %s is sunny", args.Location), nil
 }
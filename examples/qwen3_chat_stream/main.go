@@ -19,7 +19,7 @@ func main() {
 	}
 	scanner := bufio.NewScanner(os.Stdin)
 
-	var messages []deepseek.ChatCompletionMessage
+	conversation := deepseek.NewConversation("")
 
 	//  To do console input in debug mode, add "console": "integratedTerminal" to launch.json
 	for {
@@ -36,15 +36,12 @@ func main() {
 			break
 		}
 
-		messages = append(messages, deepseek.ChatCompletionMessage{
-			Role:    deepseek.ChatMessageRoleUser,
-			Content: input,
-		})
+		conversation.AddUser(input)
 
 		request := deepseek.StreamChatCompletionRequest{
 			Model: deepseek.QWEN3_235B_A22B, // https://help.aliyun.com/zh/model-studio/models
 			// Model:    "qwen3-32b",
-			Messages: messages,
+			Messages: conversation.Messages(),
 			// EnableThink: true,  开启思考模式
 		}
 
@@ -53,9 +50,9 @@ func main() {
 		if err != nil {
 			log.Fatalf("ChatCompletionStream failed: %v", err)
 		}
-		defer stream.Close()
 
 		fmt.Print("Qwen3: ")
+		var acc deepseek.StreamAccumulator
 		for {
 			response, err := stream.Recv()
 			if errors.Is(err, io.EOF) {
@@ -66,14 +63,12 @@ func main() {
 				log.Fatalf("ChatCompletionStream stream.Recv() failed: %v", err)
 			}
 
-			if len(response.Choices) > 0 && response.Choices[0].FinishReason == "" {
-				content := response.Choices[0].Delta.Content
-				messages = append(messages, deepseek.ChatCompletionMessage{
-					Role:    deepseek.ChatMessageRoleAssistant,
-					Content: content,
-				})
-				fmt.Print(content)
+			acc.Add(response)
+			if len(response.Choices) > 0 {
+				fmt.Print(response.Choices[0].Delta.Content)
 			}
 		}
+		stream.Close()
+		conversation.AddStream(&acc)
 	}
 }
@@ -19,7 +19,7 @@ func main() {
 	}
 	scanner := bufio.NewScanner(os.Stdin)
 
-	var messages []deepseek.ChatCompletionMessage
+	conversation := deepseek.NewConversation("")
 
 	//  To do console input in debug mode, add "console": "integratedTerminal" to launch.json
 	for {
@@ -37,14 +37,11 @@ func main() {
 			break
 		}
 
-		messages = append(messages, deepseek.ChatCompletionMessage{
-			Role:    deepseek.ChatMessageRoleUser,
-			Content: input,
-		})
+		conversation.AddUser(input)
 
 		request := deepseek.StreamChatCompletionRequest{
 			Model:    deepseek.QwQ_plus_latest,
-			Messages: messages,
+			Messages: conversation.Messages(),
 		}
 
 		ctx := context.Background()
@@ -52,9 +49,9 @@ func main() {
 		if err != nil {
 			log.Fatalf("ChatCompletionStream failed: %v", err)
 		}
-		defer stream.Close()
 
 		fmt.Print("QWQ: ")
+		var acc deepseek.StreamAccumulator
 		for {
 			response, err := stream.Recv()
 			if errors.Is(err, io.EOF) {
@@ -65,14 +62,12 @@ func main() {
 				log.Fatalf("ChatCompletionStream stream.Recv() failed: %v", err)
 			}
 
-			if len(response.Choices) > 0 && response.Choices[0].FinishReason == "" {
-				content := response.Choices[0].Delta.Content
-				messages = append(messages, deepseek.ChatCompletionMessage{
-					Role:    deepseek.ChatMessageRoleAssistant,
-					Content: content,
-				})
-				fmt.Print(content)
+			acc.Add(response)
+			if len(response.Choices) > 0 {
+				fmt.Print(response.Choices[0].Delta.Content)
 			}
 		}
+		stream.Close()
+		conversation.AddStream(&acc)
 	}
 }
@@ -1,12 +1,14 @@
 package deepseek
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"io"
+	"math"
 	"net/http"
-	"strconv"
+	"time"
 
 	deepseek "github.com/p9966/go-deepseek/internal"
 )
@@ -14,18 +16,18 @@ import (
 const finCompletionSuffix = "/beta/completions"
 
 type FINCompletionRequest struct {
-	Model            string    `json:"model"`                       // Required, 模型的 ID
-	Prompt           string    `json:"prompt"`                      // Required, 用于生成完成内容的提示
-	Echo             bool      `json:"echo,omitempty"`              // Optional, 是否返回输入的提示内容
-	FrequencyPenalty float64   `json:"frequency_penalty,omitempty"` // Optional, 控制生成内容的重复性，取值范围 [-2, 2]
-	Logprobs         int       `json:"logprobs,omitempty"`          // Optional, 制定输出中包含 logprobs 最可能输出 token 的对数概率，包含采样的 token。例如，如果 logprobs 是 20，API 将返回一个包含 20 个最可能的 token 的列表。API 将始终返回采样 token 的对数概率，因此响应中可能会有最多 logprobs+1 个元素。logprobs 的最大值是 20
-	MaxTokens        int       `json:"max_tokens,omitempty"`        // Optional, 生成内容的最大长度
-	PresencePenalty  float64   `json:"presence_penalty,omitempty"`  // Optional, 控制生成内容的多样性，取值范围 [-2, 2]
-	Stop             *[]string `json:"stop,omitempty"`              // Optional, 停止生成内容的字符串或字符串数组
-	Stream           bool      `json:"stream,omitempty"`            // Optional, 是否流式返回结果
-	Suffix           *string   `json:"suffix,omitempty"`            // Optional, 制定被补全内容的后缀。
-	Temperature      float64   `json:"temperature,omitempty"`       // Optional, 采样温度，介于 0 和 2 之间。更高的值，如 0.8，会使输出更随机，而更低的值，如 0.2，会使其更加集中和确定。 我们通常建议可以更改这个值或者更改 top_p，但不建议同时对两者进行修改。
-	TopP             float64   `json:"top_p,omitempty"`             // Optional, 作为调节采样温度的替代方案，模型会考虑前 top_p 概率的 token 的结果。所以 0.1 就意味着只有包括在最高 10% 概率中的 token 会被考虑。 我们通常建议修改这个值或者更改 temperature，但不建议同时对两者进行修改。
+	Model            string        `json:"model"`                       // Required, 模型的 ID
+	Prompt           string        `json:"prompt"`                      // Required, 用于生成完成内容的提示
+	Echo             bool          `json:"echo,omitempty"`              // Optional, 是否返回输入的提示内容
+	FrequencyPenalty float64       `json:"frequency_penalty,omitempty"` // Optional, 控制生成内容的重复性，取值范围 [-2, 2]
+	Logprobs         int           `json:"logprobs,omitempty"`          // Optional, 制定输出中包含 logprobs 最可能输出 token 的对数概率，包含采样的 token。例如，如果 logprobs 是 20，API 将返回一个包含 20 个最可能的 token 的列表。API 将始终返回采样 token 的对数概率，因此响应中可能会有最多 logprobs+1 个元素。logprobs 的最大值是 20
+	MaxTokens        int           `json:"max_tokens,omitempty"`        // Optional, 生成内容的最大长度
+	PresencePenalty  float64       `json:"presence_penalty,omitempty"`  // Optional, 控制生成内容的多样性，取值范围 [-2, 2]
+	Stop             StopSequences `json:"stop,omitempty"`              // Optional, 停止生成内容的字符串或字符串数组
+	Stream           bool          `json:"stream,omitempty"`            // Optional, 是否流式返回结果
+	Suffix           string        `json:"suffix,omitempty"`            // Optional, 制定被补全内容的后缀。
+	Temperature      float64       `json:"temperature,omitempty"`       // Optional, 采样温度，介于 0 和 2 之间。更高的值，如 0.8，会使输出更随机，而更低的值，如 0.2，会使其更加集中和确定。 我们通常建议可以更改这个值或者更改 top_p，但不建议同时对两者进行修改。
+	TopP             float64       `json:"top_p,omitempty"`             // Optional, 作为调节采样温度的替代方案，模型会考虑前 top_p 概率的 token 的结果。所以 0.1 就意味着只有包括在最高 10% 概率中的 token 会被考虑。 我们通常建议修改这个值或者更改 temperature，但不建议同时对两者进行修改。
 }
 
 type FINCompletionResponse struct {
@@ -35,14 +37,7 @@ type FINCompletionResponse struct {
 	Model             string                `json:"model"`
 	SystemFingerprint string                `json:"system_fingerprint"`
 	Object            string                `json:"object"`
-	Usage             struct {
-		CompletionTokens      int                                   `json:"completion_tokens"`
-		PromptTokens          int                                   `json:"prompt_tokens"`
-		PromptCacheHitTokens  int                                   `json:"prompt_cache_hit_tokens"`
-		PromptCacheMissTokens int                                   `json:"prompt_cache_miss_tokens"`
-		TotalTokens           int                                   `json:"total_tokens"`
-		PromptTokensDetails   FINCompletionUsagePromptTokensDetails `json:"prompt_tokens_details"`
-	} `json:"usage"`
+	Usage             Usage                 `json:"usage"`
 }
 
 type FINCompletionChoice struct {
@@ -52,13 +47,54 @@ type FINCompletionChoice struct {
 	Text         string                      `json:"text"`
 }
 
-type FINCompletionUsagePromptTokensDetails struct {
-	CachedTokens int `json:"cached_tokens"`
-}
+// FIMCompletionRequest is the correctly spelled name of FINCompletionRequest.
+type FIMCompletionRequest = FINCompletionRequest
+
+// FIMCompletionResponse is the correctly spelled name of FINCompletionResponse.
+type FIMCompletionResponse = FINCompletionResponse
+
+type FINCompletionUsagePromptTokensDetails = PromptTokensDetails
 
 type FINCompletionChoiceLogprobs struct {
-	Tokens        []string  `json:"tokens"`
-	TokenLogprobs []float64 `json:"token_logprobs"`
+	Tokens        []string             `json:"tokens"`
+	TokenLogprobs []float64            `json:"token_logprobs"`
+	TopLogprobs   []map[string]float64 `json:"top_logprobs,omitempty"` // Most likely alternatives for each token
+	TextOffset    []int                `json:"text_offset,omitempty"`  // Offset of each token in the completion text
+}
+
+// Confidence returns the geometric mean probability of the sampled tokens, or
+// 0 when no log probabilities were returned.
+func (l FINCompletionChoiceLogprobs) Confidence() float64 {
+	if len(l.TokenLogprobs) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, lp := range l.TokenLogprobs {
+		sum += lp
+	}
+	return math.Exp(sum / float64(len(l.TokenLogprobs)))
+}
+
+// StopSequences holds the stop sequences of a request. It accepts either a
+// single string or a list of strings in JSON.
+type StopSequences []string
+
+func (s *StopSequences) UnmarshalJSON(data []byte) error {
+	if string(bytes.TrimSpace(data)) == "null" {
+		*s = nil
+		return nil
+	}
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = StopSequences{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
 }
 
 func (c *Client) CreateFINCompletion(ctx context.Context, req *FINCompletionRequest) (*FINCompletionResponse, error) {
@@ -71,6 +107,7 @@ func (c *Client) CreateFINCompletion(ctx context.Context, req *FINCompletionRequ
 		return nil, err
 	}
 
+	start := time.Now()
 	response, err := c.Do(request)
 	if err != nil {
 		return nil, err
@@ -79,7 +116,7 @@ func (c *Client) CreateFINCompletion(ctx context.Context, req *FINCompletionRequ
 	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
-		return nil, errors.New("unexpected status code: " + strconv.Itoa(response.StatusCode))
+		return nil, newAPIError(response)
 	}
 
 	buf, err := io.ReadAll(response.Body)
@@ -91,5 +128,11 @@ func (c *Client) CreateFINCompletion(ctx context.Context, req *FINCompletionRequ
 	if err := json.Unmarshal(buf, &finResponse); err != nil {
 		return nil, err
 	}
+	c.recordUsage(ctx, finCompletionSuffix, req.Model, finResponse.Usage, start)
 	return &finResponse, nil
 }
+
+// CreateFIMCompletion is the correctly spelled name of CreateFINCompletion.
+func (c *Client) CreateFIMCompletion(ctx context.Context, req *FIMCompletionRequest) (*FIMCompletionResponse, error) {
+	return c.CreateFINCompletion(ctx, req)
+}
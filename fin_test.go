@@ -0,0 +1,55 @@
+package deepseek
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFIMCompletionJSON(t *testing.T) {
+	var req FIMCompletionRequest
+	if err := json.Unmarshal([]byte(`{"model":"deepseek-chat","prompt":"def f(","stop":"\n"}`), &req); err != nil {
+		t.Fatal(err)
+	}
+	if len(req.Stop) != 1 || req.Stop[0] != "\n" {
+		t.Fatalf("single stop string not decoded: %q", req.Stop)
+	}
+
+	body := `{"choices":[{"text":"x)","logprobs":{"tokens":["x",")"],"token_logprobs":[0,0],"top_logprobs":[{"x":0},{")":0}],"text_offset":[0,1]}}],
+		"usage":{"prompt_tokens":5,"completion_tokens":2,"total_tokens":7,"prompt_tokens_details":{"cached_tokens":3}}}`
+	var resp FIMCompletionResponse
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	logprobs := resp.Choices[0].Logprobs
+	if logprobs.TextOffset[1] != 1 || len(logprobs.TopLogprobs) != 2 || logprobs.Confidence() != 1 {
+		t.Fatalf("logprobs not decoded: %+v", logprobs)
+	}
+	if resp.Usage.PromptTokensDetails == nil || resp.Usage.PromptTokensDetails.CachedTokens != 3 {
+		t.Fatalf("usage not decoded: %+v", resp.Usage)
+	}
+}
+
+func TestStopSequencesRoundTrip(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{`{"stop":null}`, `{}`},
+		{`{"stop":"\n"}`, `{"stop":["\n"]}`},
+		{`{"stop":["a","b"]}`, `{"stop":["a","b"]}`},
+	}
+	for _, tt := range tests {
+		var req struct {
+			Stop StopSequences `json:"stop,omitempty"`
+		}
+		if err := json.Unmarshal([]byte(tt.in), &req); err != nil {
+			t.Fatal(err)
+		}
+		out, err := json.Marshal(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != tt.out {
+			t.Errorf("%s round-tripped to %s, want %s", tt.in, out, tt.out)
+		}
+	}
+}
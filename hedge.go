@@ -0,0 +1,314 @@
+package deepseek
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HedgeLedgerTag tags the ledger entries recording the cost of the losing
+// request of a hedged pair.
+const HedgeLedgerTag = "hedge"
+
+// hedgeWindow is the number of recent latencies a HedgePolicy keeps per
+// endpoint, and hedgeMinSamples how many it needs before using Percentile.
+const (
+	hedgeWindow     = 200
+	hedgeMinSamples = 20
+)
+
+// HedgePolicy cuts tail latency by sending a duplicate of a request that has
+// not completed after a delay. Whichever completes first is returned and the
+// other is canceled. Set it as Client.Hedge.
+//
+// A zero Delay does not hedge until Percentile has enough samples, so a zero
+// HedgePolicy only records latencies.
+//
+// A non-streamed request completes when its whole body has arrived, and a
+// streamed one when its response headers have. The loser's usage is recorded
+// in the client's ledger, tagged HedgeLedgerTag; when it was canceled its
+// prompt tokens are estimated and the tokens it generated are unknown.
+type HedgePolicy struct {
+	Delay      time.Duration // Optional: wait before sending the duplicate, and the fallback until Percentile has enough samples
+	Percentile float64       // Optional: wait for this percentile of recent latencies of the endpoint instead, e.g. 0.95
+	BaseUrl    string        // Optional: send the duplicate to this backend with the same API instead
+	AuthToken  string        // Optional: token of BaseUrl, defaults to the token of the request
+	Paths      []string      // Optional: endpoint suffixes to hedge, defaults to chat and FIM completions
+
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+	stats     HedgeStats
+}
+
+// HedgeStats counts hedged requests.
+type HedgeStats struct {
+	Requests   int64   // Requests eligible for hedging
+	Hedged     int64   // Requests for which a duplicate was sent
+	HedgeWins  int64   // Requests answered by the duplicate
+	ExtraUsage Usage   // Usage of the losing requests, estimated when canceled
+	ExtraCost  Decimal // Estimated cost of ExtraUsage
+}
+
+// Stats returns the hedged requests so far.
+func (h *HedgePolicy) Stats() HedgeStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.stats
+}
+
+func (h *HedgePolicy) applies(req *http.Request) bool {
+	if req.Method != http.MethodPost || req.Body == nil {
+		return false
+	}
+	paths := h.Paths
+	if paths == nil {
+		paths = []string{chatCompletionSuffix, finCompletionSuffix}
+	}
+	for _, p := range paths {
+		if strings.HasSuffix(req.URL.Path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns how long to wait for the request to path before hedging.
+func (h *HedgePolicy) delay(path string) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	samples := h.latencies[path]
+	if h.Percentile <= 0 || len(samples) < hedgeMinSamples {
+		return h.Delay
+	}
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+	i := int(math.Ceil(h.Percentile*float64(len(sorted)))) - 1
+	return sorted[max(0, min(i, len(sorted)-1))]
+}
+
+func (h *HedgePolicy) observe(path string, latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.latencies == nil {
+		h.latencies = make(map[string][]time.Duration)
+	}
+	samples := append(h.latencies[path], latency)
+	if len(samples) > hedgeWindow {
+		samples = samples[len(samples)-hedgeWindow:]
+	}
+	h.latencies[path] = samples
+}
+
+type hedgeResult struct {
+	resp   *http.Response
+	err    error
+	hedge  bool
+	cancel context.CancelFunc
+}
+
+func (r hedgeResult) failed() bool {
+	return r.err != nil || r.resp.StatusCode >= 500
+}
+
+// wrap returns the doer hedging the requests c sends through next.
+func (h *HedgePolicy) wrap(c *Client, next Doer) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		if !h.applies(req) {
+			return next.Do(req)
+		}
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		h.mu.Lock()
+		h.stats.Requests++
+		h.mu.Unlock()
+
+		start := time.Now()
+		results := make(chan hedgeResult, 2)
+		cancels := make(map[bool]context.CancelFunc, 2)
+		launch := func(r *http.Request, hedge bool) {
+			ctx, cancel := context.WithCancel(req.Context())
+			cancels[hedge] = cancel
+			r = r.WithContext(ctx)
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			go func() {
+				resp, err := next.Do(r)
+				if err == nil && resp.StatusCode == http.StatusOK && !isStreamContentType(resp.Header.Get("Content-Type")) {
+					var data []byte
+					data, err = io.ReadAll(resp.Body)
+					resp.Body.Close()
+					resp.Body = io.NopCloser(bytes.NewReader(data))
+				}
+				results <- hedgeResult{resp: resp, err: err, hedge: hedge, cancel: cancel}
+			}()
+		}
+
+		launch(req.Clone(req.Context()), false)
+		var timeout <-chan time.Time
+		if delay := h.delay(req.URL.Path); delay > 0 {
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		pending := 1
+		var failure hedgeResult
+		for {
+			select {
+			case <-timeout:
+				hedgeReq, err := h.hedgeRequest(c, req)
+				if err != nil {
+					continue
+				}
+				launch(hedgeReq, true)
+				pending++
+				h.mu.Lock()
+				h.stats.Hedged++
+				h.mu.Unlock()
+			case r := <-results:
+				pending--
+				h.discard(failure)
+				if r.failed() && pending > 0 {
+					// Wait for the other request.
+					failure = r
+					continue
+				}
+				elapsed := time.Since(start)
+				if pending > 0 {
+					// The loser may take a while to notice the cancellation,
+					// so it is released without holding up the winner.
+					cancels[!r.hedge]()
+					go h.lose(c, req, body, start, results)
+				}
+				if r.err != nil {
+					r.cancel()
+					return nil, r.err
+				}
+				// When the duplicate wins, elapsed is how long the primary ran
+				// before it was canceled: a lower bound on its latency, and
+				// at least the delay, so the slow tail stays in the samples.
+				h.observe(req.URL.Path, elapsed)
+				if r.hedge {
+					h.mu.Lock()
+					h.stats.HedgeWins++
+					h.mu.Unlock()
+				}
+				r.resp.Body = &cancelBody{ReadCloser: r.resp.Body, cancel: r.cancel}
+				return r.resp, nil
+			}
+		}
+	})
+}
+
+// hedgeRequest returns the duplicate of req, sent to BaseUrl if it is set.
+func (h *HedgePolicy) hedgeRequest(c *Client, req *http.Request) (*http.Request, error) {
+	dup := req.Clone(req.Context())
+	if h.BaseUrl == "" {
+		return dup, nil
+	}
+	u, err := url.Parse(h.BaseUrl)
+	if err != nil {
+		return nil, err
+	}
+	path := req.URL.Path
+	if base, err := url.Parse(c.BaseUrl); err == nil {
+		path = strings.TrimPrefix(path, strings.TrimSuffix(base.Path, "/"))
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + strings.TrimLeft(path, "/")
+	u.RawPath = ""
+	u.RawQuery = req.URL.RawQuery
+	dup.URL, dup.Host = u, ""
+	if h.AuthToken != "" {
+		dup.Header.Set("Authorization", h.AuthToken)
+	}
+	return dup, nil
+}
+
+// discard releases a result that will not be returned.
+func (h *HedgePolicy) discard(r hedgeResult) {
+	if r.cancel == nil {
+		return
+	}
+	if r.resp != nil {
+		r.resp.Body.Close()
+	}
+	r.cancel()
+}
+
+// lose waits for the request that lost the race, releases it and records its
+// usage.
+func (h *HedgePolicy) lose(c *Client, req *http.Request, body []byte, start time.Time, results <-chan hedgeResult) {
+	r := <-results
+	var usage Usage
+	var ok bool
+	if r.err == nil && r.resp.StatusCode == http.StatusOK {
+		data, _ := io.ReadAll(r.resp.Body)
+		usage, ok = responseUsage(data)
+	}
+	h.discard(r)
+
+	var prompt struct {
+		ChatCompletionRequest
+		Prompt string `json:"prompt"`
+		Suffix string `json:"suffix"`
+	}
+	_ = json.Unmarshal(body, &prompt)
+	if !ok {
+		// The request was canceled or failed before reporting its usage;
+		// the prompt is billed once the provider starts processing it.
+		tokenizer := NewTokenizer(prompt.Model)
+		if strings.HasSuffix(req.URL.Path, finCompletionSuffix) {
+			usage.PromptTokens = tokenizer.CountFIM(&FINCompletionRequest{Prompt: prompt.Prompt, Suffix: prompt.Suffix})
+		} else {
+			usage.PromptTokens = tokenizer.CountRequest(&prompt.ChatCompletionRequest)
+		}
+		usage.TotalTokens = usage.PromptTokens
+	}
+
+	cost, _, _ := EstimateCost(prompt.Model, usage, start)
+	h.mu.Lock()
+	h.stats.ExtraUsage.PromptTokens += usage.PromptTokens
+	h.stats.ExtraUsage.CompletionTokens += usage.CompletionTokens
+	h.stats.ExtraUsage.TotalTokens += usage.TotalTokens
+	h.stats.ExtraCost = h.stats.ExtraCost.Add(cost)
+	h.mu.Unlock()
+
+	if c.Ledger != nil {
+		labels, _ := req.Context().Value(ledgerLabelsKey{}).(ledgerLabels)
+		endpoint := req.URL.Path
+		for _, p := range []string{chatCompletionSuffix, finCompletionSuffix} {
+			if strings.HasSuffix(endpoint, p) {
+				endpoint = p
+			}
+		}
+		c.Ledger.Record(LedgerEntry{
+			Time:     start,
+			Endpoint: endpoint,
+			Model:    prompt.Model,
+			User:     labels.user,
+			Tags:     append(slices.Clone(labels.tags), HedgeLedgerTag),
+			Usage:    usage,
+			Latency:  time.Since(start),
+		})
+	}
+}
+
+// cancelBody cancels the context of its request once closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,209 @@
+package deepseek
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const hedgeReply = `{"choices":[{"message":{"role":"assistant","content":"ok"}}],"usage":{"prompt_tokens":8,"completion_tokens":1,"total_tokens":9}}`
+
+func TestHedgeReturnsFirstResponse(t *testing.T) {
+	var calls atomic.Int32
+	canceled := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			// The server notices the canceled request once the body is read.
+			io.ReadAll(r.Body)
+			<-r.Context().Done()
+			close(canceled)
+			return
+		}
+		w.Write([]byte(hedgeReply))
+	}))
+	defer slow.Close()
+
+	ledger := &UsageLedger{}
+	client := &Client{BaseUrl: slow.URL, Ledger: ledger, Hedge: &HedgePolicy{Delay: 20 * time.Millisecond}}
+	req := &ChatCompletionRequest{Model: DeepSeekChat, Messages: []ChatCompletionMessage{{Role: ChatMessageRoleUser, Content: "Why is the sky blue?"}}}
+
+	resp, err := client.CreateChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Choices[0].Message.Content != "ok" {
+		t.Fatalf("content = %q", resp.Choices[0].Message.Content)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("losing request was not canceled")
+	}
+
+	// The sample is the primary's time until it was canceled, not the
+	// duplicate's own latency.
+	if samples := client.Hedge.latencies[chatCompletionSuffix]; len(samples) != 1 || samples[0] < 20*time.Millisecond {
+		t.Fatalf("latency samples = %v, want one of at least the delay", samples)
+	}
+	// The loser is accounted for after the winner has been returned.
+	stats := waitHedgeStats(t, client.Hedge, func(stats HedgeStats) bool { return stats.ExtraUsage.PromptTokens > 0 })
+	if stats.Requests != 1 || stats.Hedged != 1 || stats.HedgeWins != 1 || stats.ExtraUsage.PromptTokens == 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	hedge := ledger.ByTag()[HedgeLedgerTag]
+	if hedge.Calls != 1 || hedge.Usage.PromptTokens != stats.ExtraUsage.PromptTokens {
+		t.Fatalf("hedge ledger = %+v", hedge)
+	}
+	if total := ledger.Total(); total.Calls != 2 {
+		t.Fatalf("ledger calls = %d, want the winner and the loser", total.Calls)
+	}
+}
+
+func TestHedgeSkipsFastRequests(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(hedgeReply))
+	}))
+	defer server.Close()
+
+	client := &Client{BaseUrl: server.URL, Hedge: &HedgePolicy{Delay: time.Second}}
+	for i := 0; i < 3; i++ {
+		if _, err := client.CreateChatCompletion(context.Background(), &ChatCompletionRequest{Model: DeepSeekChat}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls.Load() != 3 || client.Hedge.Stats().Hedged != 0 {
+		t.Fatalf("calls = %d, stats = %+v", calls.Load(), client.Hedge.Stats())
+	}
+}
+
+func TestHedgeToOtherBackend(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		<-r.Context().Done()
+	}))
+	defer primary.Close()
+	var auth string
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Write([]byte(hedgeReply))
+	}))
+	defer backup.Close()
+
+	client := &Client{BaseUrl: primary.URL, AuthToken: "primary-key", Hedge: &HedgePolicy{Delay: 10 * time.Millisecond, BaseUrl: backup.URL, AuthToken: "backup-key"}}
+	if _, err := client.CreateChatCompletion(context.Background(), &ChatCompletionRequest{Model: DeepSeekChat}); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "backup-key" {
+		t.Fatalf("backup authorization = %q", auth)
+	}
+}
+
+func TestHedgePercentileDelay(t *testing.T) {
+	h := &HedgePolicy{Delay: time.Second, Percentile: 0.9}
+	if d := h.delay(chatCompletionSuffix); d != time.Second {
+		t.Fatalf("delay without samples = %s", d)
+	}
+	for i := 1; i <= 100; i++ {
+		h.observe(chatCompletionSuffix, time.Duration(i)*time.Millisecond)
+	}
+	if d := h.delay(chatCompletionSuffix); d != 90*time.Millisecond {
+		t.Fatalf("p90 delay = %s", d)
+	}
+}
+
+func TestHedgeDoesNotWaitForLoser(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	defer close(release)
+	transport := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if calls.Add(1) == 1 {
+			// A transport that ignores cancellation.
+			<-release
+			return nil, context.Canceled
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(hedgeReply))}, nil
+	})
+	client := &Client{BaseUrl: "http://deepseek.test", httpClient: &http.Client{Transport: transport}, Hedge: &HedgePolicy{Delay: 10 * time.Millisecond}}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.CreateChatCompletion(context.Background(), &ChatCompletionRequest{Model: DeepSeekChat})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("winner held up by the loser")
+	}
+}
+
+func TestHedgeURLWithBasePath(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		<-r.Context().Done()
+	}))
+	defer primary.Close()
+	paths := make(chan string, 1)
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.Path
+		w.Write([]byte(hedgeReply))
+	}))
+	defer backup.Close()
+
+	client := &Client{BaseUrl: primary.URL + "/", Hedge: &HedgePolicy{Delay: 10 * time.Millisecond, BaseUrl: backup.URL + "/v1/"}}
+	if _, err := client.CreateChatCompletion(context.Background(), &ChatCompletionRequest{Model: DeepSeekChat}); err != nil {
+		t.Fatal(err)
+	}
+	if path := <-paths; path != "/v1"+chatCompletionSuffix {
+		t.Fatalf("duplicate sent to %q", path)
+	}
+}
+
+func TestHedgeZeroDelayWaitsForSamples(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(5 * time.Millisecond)
+		w.Write([]byte(hedgeReply))
+	}))
+	defer server.Close()
+
+	client := &Client{BaseUrl: server.URL, Hedge: &HedgePolicy{}}
+	for i := 0; i < 3; i++ {
+		if _, err := client.CreateChatCompletion(context.Background(), &ChatCompletionRequest{Model: DeepSeekChat}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls.Load() != 3 || client.Hedge.Stats().Hedged != 0 {
+		t.Fatalf("calls = %d, stats = %+v", calls.Load(), client.Hedge.Stats())
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// waitHedgeStats polls the stats of h until done accepts them.
+func waitHedgeStats(t *testing.T, h *HedgePolicy, done func(HedgeStats) bool) HedgeStats {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		stats := h.Stats()
+		if done(stats) || time.Now().After(deadline) {
+			return stats
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
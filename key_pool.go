@@ -0,0 +1,343 @@
+package deepseek
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrNoKeyAvailable is returned by a KeyPool when every key is benched.
+var ErrNoKeyAvailable = errors.New("no API key available")
+
+// KeyStrategy selects the key of the next request of a KeyPool.
+type KeyStrategy int
+
+const (
+	RoundRobin  KeyStrategy = iota // keys take turns
+	LeastLoaded                    // the key with the fewest requests in flight, then the fewest requests
+)
+
+// KeyStats reports the usage of a KeyPool key.
+type KeyStats struct {
+	Key          string // The key with all but its last four characters masked
+	Requests     int64
+	Failures     int64 // Responses other than 200 and transport errors
+	InFlight     int
+	Usage        Usage     // Token usage reported by the responses
+	LastStatus   int       // Status code of the last response
+	BenchedUntil time.Time // Zero unless the key is benched
+}
+
+// KeyPool spreads requests over several API keys. Its middleware replaces the
+// Authorization header of every request, so install it with
+// client.Use(pool.Middleware()) on a client without its own AuthToken.
+//
+// A key answered with HTTP 429 is benched for the Retry-After delay or
+// RateLimitBench, and a key answered with HTTP 401 or 402 for AuthBench. The
+// request is then retried with another key when its body can be replayed.
+// A successful request stays in flight on its key until its response body is
+// read to EOF or closed, so callers must close it. A KeyPool is safe for
+// concurrent use.
+type KeyPool struct {
+	Strategy       KeyStrategy
+	RateLimitBench time.Duration                                 // Optional: bench after HTTP 429 without Retry-After, defaults to one minute
+	AuthBench      time.Duration                                 // Optional: bench after HTTP 401 or 402, defaults to one hour
+	OnBench        func(key string, status int, until time.Time) // Optional: called with the masked key when a key is benched
+
+	mu   sync.Mutex
+	keys []*poolKey
+	next int
+}
+
+type poolKey struct {
+	key string
+	KeyStats
+}
+
+// NewKeyPool returns a round-robin pool of keys.
+func NewKeyPool(keys ...string) *KeyPool {
+	p := &KeyPool{}
+	for _, key := range keys {
+		p.keys = append(p.keys, &poolKey{key: key, KeyStats: KeyStats{Key: maskKey(key)}})
+	}
+	return p
+}
+
+// Stats returns the usage of every key, in the order they were given.
+func (p *KeyPool) Stats() []KeyStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	out := make([]KeyStats, len(p.keys))
+	for i, k := range p.keys {
+		out[i] = k.KeyStats
+		if !k.BenchedUntil.After(now) {
+			out[i].BenchedUntil = time.Time{}
+		}
+	}
+	return out
+}
+
+// Middleware returns the middleware authenticating requests with the pool.
+func (p *KeyPool) Middleware() Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			for attempt := 0; ; attempt++ {
+				k := p.acquire()
+				if k == nil {
+					return nil, ErrNoKeyAvailable
+				}
+				attemptReq := req.Clone(req.Context())
+				attemptReq.Header.Set("Authorization", k.key)
+				if attempt > 0 {
+					body, err := req.GetBody()
+					if err != nil {
+						p.release(k, 0, nil)
+						return nil, err
+					}
+					attemptReq.Body = body
+				}
+
+				resp, err := next.Do(attemptReq)
+				if err != nil {
+					p.release(k, 0, nil)
+					return nil, err
+				}
+				if resp.StatusCode == http.StatusOK {
+					resp.Body = &keyBody{ReadCloser: resp.Body, whole: !isStreamContentType(resp.Header.Get("Content-Type")), onDone: func(data []byte) {
+						usage, _ := responseUsage(data)
+						p.release(k, http.StatusOK, &usage)
+					}}
+					return resp, nil
+				}
+				benched := p.bench(k, resp)
+				p.release(k, resp.StatusCode, nil)
+				if !benched || req.GetBody == nil || attempt >= len(p.keys)-1 || !p.available() {
+					return resp, nil
+				}
+				resp.Body.Close()
+			}
+		})
+	}
+}
+
+// acquire picks the key of the next request, or nil if all are benched.
+func (p *KeyPool) acquire() *poolKey {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	var best *poolKey
+	for i := range p.keys {
+		k := p.keys[(p.next+i)%len(p.keys)]
+		if k.BenchedUntil.After(now) {
+			continue
+		}
+		if p.Strategy == RoundRobin {
+			best = k
+			break
+		}
+		if best == nil || k.InFlight < best.InFlight || (k.InFlight == best.InFlight && k.Requests < best.Requests) {
+			best = k
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	for i, k := range p.keys {
+		if k == best {
+			p.next = i + 1
+		}
+	}
+	best.Requests++
+	best.InFlight++
+	return best
+}
+
+func (p *KeyPool) available() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	for _, k := range p.keys {
+		if !k.BenchedUntil.After(now) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *KeyPool) release(k *poolKey, status int, usage *Usage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	k.InFlight--
+	k.LastStatus = status
+	if status != http.StatusOK {
+		k.Failures++
+	}
+	if usage != nil {
+		k.Usage.PromptTokens += usage.PromptTokens
+		k.Usage.CompletionTokens += usage.CompletionTokens
+		k.Usage.TotalTokens += usage.TotalTokens
+		k.Usage.PromptCacheHitTokens += usage.PromptCacheHitTokens
+		k.Usage.PromptCacheMissTokens += usage.PromptCacheMissTokens
+	}
+}
+
+// bench benches k if resp shows it is rate limited, invalid or out of credit.
+func (p *KeyPool) bench(k *poolKey, resp *http.Response) bool {
+	var d time.Duration
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		d = p.RateLimitBench
+		if d <= 0 {
+			d = time.Minute
+		}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			d = time.Duration(seconds) * time.Second
+		}
+	case http.StatusUnauthorized, http.StatusPaymentRequired:
+		d = p.AuthBench
+		if d <= 0 {
+			d = time.Hour
+		}
+	default:
+		return false
+	}
+	until := time.Now().Add(d)
+	p.mu.Lock()
+	k.BenchedUntil = until
+	p.mu.Unlock()
+	if p.OnBench != nil {
+		p.OnBench(k.Key, resp.StatusCode, until)
+	}
+	return true
+}
+
+// maxKeyBodySize bounds the non-streamed response a keyBody buffers to find
+// its usage; the usage of a larger response is not recorded.
+const maxKeyBodySize = 4 << 20
+
+// keyBody passes a response body through and hands onDone what may report its
+// usage once the body reaches EOF or is closed, whichever is first. A
+// non-streamed body is kept whole, up to maxKeyBodySize. Of a streamed one
+// only the last complete line mentioning usage and the line being read are
+// kept, so long streams are not buffered.
+type keyBody struct {
+	io.ReadCloser
+	whole  bool
+	onDone func([]byte)
+
+	mu       sync.Mutex
+	line     []byte // the body so far when whole, else the incomplete line being read
+	last     []byte // the last complete line mentioning usage
+	overflow bool   // the whole body exceeded maxKeyBodySize
+	done     bool
+}
+
+func (b *keyBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.done {
+		return n, err
+	}
+	data := p[:n]
+	switch {
+	case b.whole:
+		if len(b.line)+len(data) > maxKeyBodySize {
+			b.overflow, b.line = true, nil
+		}
+		if !b.overflow {
+			b.line = append(b.line, data...)
+		}
+	default:
+		for {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				break
+			}
+			b.line = append(b.line, data[:i]...)
+			b.keep()
+			data = data[i+1:]
+		}
+		b.line = append(b.line, data...)
+	}
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *keyBody) Close() error {
+	b.mu.Lock()
+	if !b.done {
+		b.finish()
+	}
+	b.mu.Unlock()
+	return b.ReadCloser.Close()
+}
+
+// keep remembers the completed line if it may report usage and starts the
+// next one. The caller holds b.mu.
+func (b *keyBody) keep() {
+	if bytes.Contains(b.line, []byte(`"usage"`)) || bytes.Contains(b.line, []byte(`"eval_count"`)) {
+		b.last = append(b.last[:0], b.line...)
+	}
+	b.line = b.line[:0]
+}
+
+// finish hands the body or usage line to onDone. The caller holds b.mu.
+func (b *keyBody) finish() {
+	b.done = true
+	if b.whole {
+		b.onDone(b.line)
+	} else {
+		b.keep()
+		b.onDone(b.last)
+	}
+	b.line, b.last = nil, nil
+}
+
+// responseUsage finds the token usage in a JSON response body, or in the last
+// event reporting it of a streamed one. Ollama counts are converted.
+func responseUsage(data []byte) (Usage, bool) {
+	parse := func(b []byte) (Usage, bool) {
+		var v struct {
+			Usage           *Usage `json:"usage"`
+			PromptEvalCount int    `json:"prompt_eval_count"`
+			EvalCount       int    `json:"eval_count"`
+		}
+		if json.Unmarshal(b, &v) != nil {
+			return Usage{}, false
+		}
+		if v.Usage != nil {
+			return *v.Usage, true
+		}
+		if v.PromptEvalCount+v.EvalCount > 0 {
+			return Usage{PromptTokens: v.PromptEvalCount, CompletionTokens: v.EvalCount, TotalTokens: v.PromptEvalCount + v.EvalCount}, true
+		}
+		return Usage{}, false
+	}
+	if usage, ok := parse(data); ok {
+		return usage, true
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := bytes.TrimSpace(bytes.TrimPrefix(bytes.TrimSpace(lines[i]), []byte("data:")))
+		if usage, ok := parse(line); ok {
+			return usage, true
+		}
+	}
+	return Usage{}, false
+}
+
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return "****"
+	}
+	return "****" + key[len(key)-4:]
+}
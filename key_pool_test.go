@@ -0,0 +1,166 @@
+package deepseek
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeyPoolRoundRobinAndUsage(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.Header.Get("Authorization")]++
+		mu.Unlock()
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`))
+	}))
+	defer server.Close()
+
+	pool := NewKeyPool("sk-aaaa1111", "sk-bbbb2222", "sk-cccc3333")
+	client := &Client{BaseUrl: server.URL}
+	client.Use(pool.Middleware())
+	req := &ChatCompletionRequest{Model: DeepSeekChat, Messages: []ChatCompletionMessage{{Role: ChatMessageRoleUser, Content: "hi"}}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.CreateChatCompletion(context.Background(), req); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for key, n := range seen {
+		if n != 10 {
+			t.Fatalf("%s served %d requests, want 10", key, n)
+		}
+	}
+	for _, s := range pool.Stats() {
+		if s.Requests != 10 || s.InFlight != 0 || s.Usage.TotalTokens != 50 {
+			t.Fatalf("stats = %+v", s)
+		}
+	}
+	if key := pool.Stats()[0].Key; key != "****1111" {
+		t.Fatalf("masked key = %s", key)
+	}
+}
+
+func TestKeyPoolBenchesKeys(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "limited":
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "revoked":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+		}
+	}))
+	defer server.Close()
+
+	var benched []int
+	pool := NewKeyPool("limited", "revoked", "good")
+	pool.OnBench = func(key string, status int, until time.Time) { benched = append(benched, status) }
+	client := &Client{BaseUrl: server.URL}
+	client.Use(pool.Middleware())
+	req := &ChatCompletionRequest{Model: DeepSeekChat, Messages: []ChatCompletionMessage{{Role: ChatMessageRoleUser, Content: "hi"}}}
+
+	// The first request is retried until it reaches the good key.
+	if _, err := client.CreateChatCompletion(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if len(benched) != 2 || benched[0] != http.StatusTooManyRequests || benched[1] != http.StatusUnauthorized {
+		t.Fatalf("benched = %v", benched)
+	}
+	stats := pool.Stats()
+	if until := time.Until(stats[0].BenchedUntil); until < 110*time.Second {
+		t.Fatalf("rate limited key benched for %s", until)
+	}
+	if _, err := client.CreateChatCompletion(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if stats = pool.Stats(); stats[2].Requests != 2 || stats[0].Requests != 1 || stats[1].Failures != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	empty := &Client{BaseUrl: server.URL}
+	empty.Use(NewKeyPool("limited").Middleware())
+	empty.CreateChatCompletion(context.Background(), req)
+	if _, err := empty.CreateChatCompletion(context.Background(), req); !errors.Is(err, ErrNoKeyAvailable) {
+		t.Fatalf("err = %v, want ErrNoKeyAvailable", err)
+	}
+}
+
+func TestKeyPoolLeastLoaded(t *testing.T) {
+	pool := NewKeyPool("a", "b")
+	pool.Strategy = LeastLoaded
+	first := pool.acquire()
+	second := pool.acquire()
+	if first == second {
+		t.Fatal("busy key was chosen over an idle one")
+	}
+	pool.release(first, http.StatusOK, nil)
+	if third := pool.acquire(); third != first {
+		t.Fatalf("got %s, want the idle key %s", third.key, first.key)
+	}
+}
+
+func TestKeyPoolReleasesStreamAtEOF(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"ok\"}}]}\n\n"))
+		w.Write([]byte("data: {\"choices\":[],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":2,\"total_tokens\":5}}\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	pool := NewKeyPool("sk-aaaa1111")
+	client := &Client{BaseUrl: server.URL}
+	client.Use(pool.Middleware())
+	req, _ := http.NewRequest(http.MethodPost, server.URL+chatCompletionSuffix, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := pool.Stats()[0]; s.InFlight != 1 {
+		t.Fatalf("in flight before reading = %d", s.InFlight)
+	}
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	if s := pool.Stats()[0]; s.InFlight != 0 || s.Usage.TotalTokens != 5 {
+		t.Fatalf("stats after EOF = %+v", s)
+	}
+	resp.Body.Close()
+	if s := pool.Stats()[0]; s.Requests != 1 || s.Usage.TotalTokens != 5 {
+		t.Fatalf("stats after close = %+v", s)
+	}
+}
+
+func TestKeyPoolUsageOfIndentedJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{\n  \"choices\": [],\n  \"usage\": {\n    \"prompt_tokens\": 3,\n    \"completion_tokens\": 2,\n    \"total_tokens\": 5\n  }\n}\n"))
+	}))
+	defer server.Close()
+
+	pool := NewKeyPool("sk-aaaa1111")
+	client := &Client{BaseUrl: server.URL}
+	client.Use(pool.Middleware())
+	if _, err := client.CreateChatCompletion(context.Background(), &ChatCompletionRequest{Model: DeepSeekChat}); err != nil {
+		t.Fatal(err)
+	}
+	if s := pool.Stats()[0]; s.InFlight != 0 || s.Usage.TotalTokens != 5 {
+		t.Fatalf("stats = %+v", s)
+	}
+}
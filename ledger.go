@@ -0,0 +1,205 @@
+package deepseek
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LedgerEntry records the usage and cost of one API call.
+type LedgerEntry struct {
+	Time     time.Time     `json:"time"`
+	Endpoint string        `json:"endpoint,omitempty"`
+	Model    string        `json:"model"`
+	User     string        `json:"user,omitempty"`
+	Tags     []string      `json:"tags,omitempty"`
+	Usage    Usage         `json:"usage"`
+	Latency  time.Duration `json:"latency"` // Nanoseconds from sending the request to receiving the usage
+	Cost     Decimal       `json:"cost"`    // Estimated cost
+	Currency string        `json:"currency,omitempty"`
+}
+
+// UsageSummary aggregates ledger entries.
+type UsageSummary struct {
+	Calls int
+	Usage Usage
+	Cost  Decimal
+}
+
+func (s *UsageSummary) add(e LedgerEntry) {
+	s.Calls++
+	s.Usage.PromptTokens += e.Usage.PromptTokens
+	s.Usage.CompletionTokens += e.Usage.CompletionTokens
+	s.Usage.TotalTokens += e.Usage.TotalTokens
+	s.Usage.PromptCacheHitTokens += e.Usage.PromptCacheHitTokens
+	s.Usage.PromptCacheMissTokens += e.Usage.PromptCacheMissTokens
+	s.Cost = s.Cost.Add(e.Cost)
+}
+
+// UsageLedger records the calls made by a Client with a Ledger set. It is safe
+// for concurrent use.
+//
+// Costs are estimates from the pricing table, computed and summed exactly as
+// Decimal amounts like the balances of GetBalance.
+//
+// Streamed chat completions are recorded only when the server reports usage,
+// which DeepSeek and most compatible providers do when the request sets
+// StreamOptions.IncludeUsage. A stream closed before its usage arrives is not
+// recorded. Ollama calls are recorded with the token counts Ollama reports;
+// they have no cost unless RegisterPricing was called for the model.
+type UsageLedger struct {
+	mu      sync.Mutex
+	entries []LedgerEntry
+	cost    Decimal
+}
+
+// NewUsageLedger creates an empty ledger.
+func NewUsageLedger() *UsageLedger {
+	return &UsageLedger{}
+}
+
+// Record appends an entry. When the entry has no cost and the pricing of its
+// model is known, the cost is computed from its usage and time.
+func (l *UsageLedger) Record(e LedgerEntry) LedgerEntry {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Cost.IsZero() {
+		if cost, currency, err := EstimateCost(e.Model, e.Usage, e.Time); err == nil {
+			e.Cost, e.Currency = cost, currency
+		}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, e)
+	l.cost = l.cost.Add(e.Cost)
+	return e
+}
+
+// TotalCost returns the sum of the cost of all entries.
+func (l *UsageLedger) TotalCost() Decimal {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cost
+}
+
+// Entries returns a copy of the recorded entries.
+func (l *UsageLedger) Entries() []LedgerEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]LedgerEntry(nil), l.entries...)
+}
+
+// Total aggregates all entries.
+func (l *UsageLedger) Total() UsageSummary {
+	var s UsageSummary
+	for _, e := range l.Entries() {
+		s.add(e)
+	}
+	return s
+}
+
+// ByUser aggregates entries per user.
+func (l *UsageLedger) ByUser() map[string]UsageSummary {
+	return l.aggregate(func(e LedgerEntry) []string { return []string{e.User} })
+}
+
+// ByTag aggregates entries per tag. An entry with several tags counts towards
+// each of them.
+func (l *UsageLedger) ByTag() map[string]UsageSummary {
+	return l.aggregate(func(e LedgerEntry) []string { return e.Tags })
+}
+
+// ByModel aggregates entries per model.
+func (l *UsageLedger) ByModel() map[string]UsageSummary {
+	return l.aggregate(func(e LedgerEntry) []string { return []string{e.Model} })
+}
+
+func (l *UsageLedger) aggregate(keys func(LedgerEntry) []string) map[string]UsageSummary {
+	out := make(map[string]UsageSummary)
+	for _, e := range l.Entries() {
+		for _, key := range keys(e) {
+			s := out[key]
+			s.add(e)
+			out[key] = s
+		}
+	}
+	return out
+}
+
+// WriteCSV writes the entries as CSV with a header row.
+func (l *UsageLedger) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := []string{"time", "endpoint", "model", "user", "tags", "prompt_tokens", "completion_tokens", "total_tokens",
+		"prompt_cache_hit_tokens", "prompt_cache_miss_tokens", "latency_ms", "cost", "currency"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, e := range l.Entries() {
+		record := []string{
+			e.Time.UTC().Format(time.RFC3339Nano),
+			e.Endpoint,
+			e.Model,
+			e.User,
+			strings.Join(e.Tags, ";"),
+			strconv.Itoa(e.Usage.PromptTokens),
+			strconv.Itoa(e.Usage.CompletionTokens),
+			strconv.Itoa(e.Usage.TotalTokens),
+			strconv.Itoa(e.Usage.PromptCacheHitTokens),
+			strconv.Itoa(e.Usage.PromptCacheMissTokens),
+			strconv.FormatInt(e.Latency.Milliseconds(), 10),
+			e.Cost.String(),
+			e.Currency,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSONL writes the entries as one JSON object per line.
+func (l *UsageLedger) WriteJSONL(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, e := range l.Entries() {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type ledgerLabelsKey struct{}
+
+type ledgerLabels struct {
+	user string
+	tags []string
+}
+
+// WithLedgerLabels attaches a user and tags to the ledger entries of calls made
+// with the returned context.
+func WithLedgerLabels(ctx context.Context, user string, tags ...string) context.Context {
+	return context.WithValue(ctx, ledgerLabelsKey{}, ledgerLabels{user: user, tags: tags})
+}
+
+func (c *Client) recordUsage(ctx context.Context, endpoint, model string, usage Usage, start time.Time) {
+	if c.Ledger == nil {
+		return
+	}
+	labels, _ := ctx.Value(ledgerLabelsKey{}).(ledgerLabels)
+	c.Ledger.Record(LedgerEntry{
+		Time:     start,
+		Endpoint: endpoint,
+		Model:    model,
+		User:     labels.user,
+		Tags:     labels.tags,
+		Usage:    usage,
+		Latency:  time.Since(start),
+	})
+}
@@ -0,0 +1,73 @@
+package deepseek
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUsageLedger(t *testing.T) {
+	usage := Usage{PromptTokens: 1_000_000, PromptCacheHitTokens: 400_000, PromptCacheMissTokens: 600_000, CompletionTokens: 1_000_000}
+	peak := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	offPeak := time.Date(2025, 3, 1, 23, 0, 0, 0, time.UTC)
+
+	ledger := NewUsageLedger()
+	ledger.Record(LedgerEntry{Time: peak, Model: DeepSeekChat, User: "alice", Tags: []string{"eval"}, Usage: usage})
+	ledger.Record(LedgerEntry{Time: offPeak, Model: DeepSeekChat, User: "bob", Tags: []string{"eval", "prod"}, Usage: usage})
+
+	byUser := ledger.ByUser()
+	if byUser["alice"].Cost.String() != "1.29" || byUser["bob"].Cost.String() != "0.645" {
+		t.Fatalf("unexpected costs: %+v", byUser)
+	}
+	if byTag := ledger.ByTag(); byTag["eval"].Calls != 2 || byTag["prod"].Calls != 1 {
+		t.Fatalf("unexpected tag aggregation: %+v", byTag)
+	}
+
+	var buf bytes.Buffer
+	if err := ledger.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 3 {
+		t.Fatalf("expected header and 2 rows, got %d lines", len(lines))
+	}
+	if total := ledger.TotalCost().String(); total != "1.935" {
+		t.Fatalf("total cost = %s", total)
+	}
+}
+
+func TestLedgerRecordsOllamaCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case ollamaChatCompletionSuffix:
+			w.Write([]byte(`{"message":{"role":"assistant","content":"hi"},"done":true,"prompt_eval_count":4,"eval_count":2}`))
+		case ollamaGenerateSuffix:
+			w.Write([]byte(`{"response":"h","done":false}` + "\n" + `{"response":"i","done":true,"prompt_eval_count":3,"eval_count":2}` + "\n"))
+		}
+	}))
+	defer server.Close()
+
+	ledger := NewUsageLedger()
+	client := &Client{BaseUrl: server.URL, Ledger: ledger}
+	if _, err := client.CreateOllamaChatCompletion(context.Background(), &OllamaChatRequest{Model: QWEN3_8B}); err != nil {
+		t.Fatal(err)
+	}
+	stream, err := client.CreateOllamaGenerateStream(context.Background(), &OllamaGenerateRequest{Model: QWEN3_8B})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := stream.Recv(); err != nil {
+			break
+		}
+	}
+	stream.Close()
+
+	entries := ledger.Entries()
+	if len(entries) != 2 || entries[0].Usage.TotalTokens != 6 || entries[1].Usage.TotalTokens != 5 || entries[1].Endpoint != ollamaGenerateSuffix {
+		t.Fatalf("entries = %+v", entries)
+	}
+}
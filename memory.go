@@ -0,0 +1,145 @@
+package deepseek
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+const summaryNotePrefix = "Summary of the earlier conversation:\n"
+
+const defaultSummaryPrompt = "You maintain the memory of a conversation. Merge the previous summary and the new messages " +
+	"into one concise summary that keeps names, facts, decisions, open questions and user preferences. " +
+	"Reply with the summary only."
+
+// Summarizer condenses messages, together with the previous rolling summary,
+// into a new summary.
+type Summarizer interface {
+	Summarize(ctx context.Context, previous string, msgs []ChatCompletionMessage) (string, error)
+}
+
+// ChatSummarizer summarizes with CreateChatCompletion.
+type ChatSummarizer struct {
+	Client *Client
+	Model  string
+	Prompt string // Optional: system prompt for the summarization call
+}
+
+func (s *ChatSummarizer) Summarize(ctx context.Context, previous string, msgs []ChatCompletionMessage) (string, error) {
+	resp, err := s.Client.CreateChatCompletion(ctx, &ChatCompletionRequest{
+		Model: s.Model,
+		Messages: []ChatCompletionMessage{
+			{Role: ChatMessageRoleSystem, Content: summaryPrompt(s.Prompt)},
+			{Role: ChatMessageRoleUser, Content: renderTranscript(previous, msgs)},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", errors.New("summarization returned no choices")
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
+
+// OllamaSummarizer summarizes with CreateOllamaChatCompletion.
+type OllamaSummarizer struct {
+	Client *Client
+	Model  string
+	Prompt string // Optional: system prompt for the summarization call
+}
+
+func (s *OllamaSummarizer) Summarize(ctx context.Context, previous string, msgs []ChatCompletionMessage) (string, error) {
+	resp, err := s.Client.CreateOllamaChatCompletion(ctx, &OllamaChatRequest{
+		Model: s.Model,
+		Messages: []OllamaChatMessage{
+			{Role: ChatMessageRoleSystem, Content: summaryPrompt(s.Prompt)},
+			{Role: ChatMessageRoleUser, Content: renderTranscript(previous, msgs)},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp.Message == nil {
+		return "", errors.New("summarization returned no message")
+	}
+	return strings.TrimSpace(resp.Message.Content), nil
+}
+
+// SummaryMemory keeps a conversation below a token threshold by summarizing
+// its oldest turns into a rolling summary instead of dropping them.
+type SummaryMemory struct {
+	Summarizer Summarizer
+	Threshold  int          // Tokens of history above which the oldest turns are summarized
+	KeepTurns  int          // Optional: most recent user turns kept verbatim, defaults to 2
+	Counter    TokenCounter // Optional: token estimator, defaults to the DeepSeek Tokenizer
+}
+
+// Compact summarizes the oldest turns of conv when its history exceeds the
+// threshold. It reports whether the conversation was compacted, and returns
+// ErrConversationChanged if turns were undone or replaced meanwhile.
+func (m *SummaryMemory) Compact(ctx context.Context, conv *Conversation) (bool, error) {
+	if m.Summarizer == nil {
+		return false, errors.New("summary memory has no summarizer")
+	}
+	counter := m.Counter
+	if counter == nil {
+		counter = NewTokenizer(DeepSeekChat)
+	}
+	keepTurns := m.KeepTurns
+	if keepTurns <= 0 {
+		keepTurns = 2
+	}
+
+	history, previous, generation := conv.snapshot()
+	if countMessageTokens(counter, history) <= m.Threshold {
+		return false, nil
+	}
+	turns := splitTurns(history)
+	if len(turns) <= keepTurns {
+		return false, nil
+	}
+
+	var old []ChatCompletionMessage
+	for _, turn := range turns[:len(turns)-keepTurns] {
+		old = append(old, turn...)
+	}
+	summary, err := m.Summarizer.Summarize(ctx, previous, old)
+	if err != nil {
+		return false, err
+	}
+	if err := conv.replaceHead(generation, len(old), summary); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func summaryPrompt(prompt string) string {
+	if prompt != "" {
+		return prompt
+	}
+	return defaultSummaryPrompt
+}
+
+func renderTranscript(previous string, msgs []ChatCompletionMessage) string {
+	var b strings.Builder
+	if previous != "" {
+		b.WriteString("Previous summary:\n")
+		b.WriteString(previous)
+		b.WriteString("\n\n")
+	}
+	b.WriteString("New messages:\n")
+	for _, msg := range msgs {
+		b.WriteString(msg.Role)
+		b.WriteString(": ")
+		b.WriteString(msg.Content)
+		for _, tc := range msg.ToolCalls {
+			b.WriteString(" [calls ")
+			b.WriteString(tc.Function.Name)
+			b.WriteString(tc.Function.Arguments)
+			b.WriteString("]")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,91 @@
+package deepseek
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSummaryMemoryCompact(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case chatCompletionSuffix:
+			json.NewEncoder(w).Encode(ChatCompletionResponse{
+				Choices: []Choice{{Message: Message{Role: ChatMessageRoleAssistant, Content: "chat summary"}}},
+			})
+		case ollamaChatCompletionSuffix:
+			var req OllamaChatRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if !strings.Contains(req.Messages[1].Content, "Previous summary:\nchat summary") {
+				t.Errorf("previous summary not forwarded: %q", req.Messages[1].Content)
+			}
+			json.NewEncoder(w).Encode(OllamaChatResponse{
+				Message: &OllamaChatMessage{Role: ChatMessageRoleAssistant, Content: "ollama summary"},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{BaseUrl: server.URL}
+	conv := NewConversation("system")
+	for i := 0; i < 4; i++ {
+		conv.AddUser(strings.Repeat("question ", 20))
+		conv.AddAssistant(strings.Repeat("answer ", 20))
+	}
+
+	summarizers := []Summarizer{
+		&ChatSummarizer{Client: client, Model: DeepSeekChat},
+		&OllamaSummarizer{Client: client, Model: QWen2_5_7b},
+	}
+	for _, summarizer := range summarizers {
+		memory := &SummaryMemory{Summarizer: summarizer, Threshold: 50, KeepTurns: 1}
+		conv.AddUser(strings.Repeat("more ", 40))
+		compacted, err := memory.Compact(context.Background(), conv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !compacted || conv.Len() != 1 {
+			t.Fatalf("expected compaction to keep one turn, got %d messages", conv.Len())
+		}
+	}
+
+	msgs := conv.Messages()
+	if conv.Summary() != "ollama summary" || msgs[1].Content != summaryNotePrefix+"ollama summary" {
+		t.Fatalf("unexpected summary messages: %+v", msgs)
+	}
+}
+
+// undoSummarizer undoes the last turn of conv and adds a new one while it
+// summarizes, like a user editing the chat during compaction.
+type undoSummarizer struct {
+	conv *Conversation
+}
+
+func (s undoSummarizer) Summarize(ctx context.Context, previous string, msgs []ChatCompletionMessage) (string, error) {
+	s.conv.Undo()
+	s.conv.AddUser("edited question")
+	s.conv.AddUser("another question")
+	return "summary", nil
+}
+
+func TestSummaryMemoryCompactDetectsUndo(t *testing.T) {
+	conv := NewConversation("")
+	for i := 0; i < 3; i++ {
+		conv.AddUser(strings.Repeat("question ", 20))
+		conv.AddAssistant(strings.Repeat("answer ", 20))
+	}
+	memory := &SummaryMemory{Summarizer: undoSummarizer{conv}, Threshold: 10, KeepTurns: 1}
+	compacted, err := memory.Compact(context.Background(), conv)
+	if !errors.Is(err, ErrConversationChanged) || compacted {
+		t.Fatalf("compacted = %v, err = %v", compacted, err)
+	}
+	if conv.Len() != 6 || conv.Summary() != "" {
+		t.Fatalf("history = %d messages, summary = %q", conv.Len(), conv.Summary())
+	}
+}
@@ -1,17 +1,62 @@
 package deepseek
 
+import "sync"
+
 const (
-	QWEN3_235B_A22B = "qwen3-235b-a22b"
-	QWEN3_32B       = "qwen3-32b"
-	QWEB3_30B_A3B   = "qwen3-30b-a3b"
-	QWEN3_14B       = "qwen3-14b"
-	QWEN3_8B        = "qwen3-8b"
-	QWEN3_4B        = "qwen3-4b"
-	QWEN3_1_7B      = "qwen3-1.7b"
-	QWEN3_0_6B      = "qwen3-0.6b"
-	DeepSeekChat    = "deepseek-chat"
-	QWen2_5_7b      = "qwen2.5:7b"
-	QwQ_plus        = "qwq-plus"
-	QwQ_plus_latest = "qwq-plus-latest"
-	QwQ_32b         = "qwq-32b"
+	QWEN3_235B_A22B  = "qwen3-235b-a22b"
+	QWEN3_32B        = "qwen3-32b"
+	QWEB3_30B_A3B    = "qwen3-30b-a3b"
+	QWEN3_14B        = "qwen3-14b"
+	QWEN3_8B         = "qwen3-8b"
+	QWEN3_4B         = "qwen3-4b"
+	QWEN3_1_7B       = "qwen3-1.7b"
+	QWEN3_0_6B       = "qwen3-0.6b"
+	DeepSeekChat     = "deepseek-chat"
+	DeepSeekReasoner = "deepseek-reasoner"
+	QWen2_5_7b       = "qwen2.5:7b"
+	QwQ_plus         = "qwq-plus"
+	QwQ_plus_latest  = "qwq-plus-latest"
+	QwQ_32b          = "qwq-32b"
+)
+
+// ModelInfo describes the limits of a model.
+type ModelInfo struct {
+	Name            string
+	ContextWindow   int // Maximum number of tokens for prompt and completion together
+	MaxOutputTokens int // Default number of tokens reserved for the completion
+}
+
+var (
+	modelRegistryMu sync.RWMutex
+	modelRegistry   = map[string]ModelInfo{
+		DeepSeekChat:     {Name: DeepSeekChat, ContextWindow: 65536, MaxOutputTokens: 8192},
+		DeepSeekReasoner: {Name: DeepSeekReasoner, ContextWindow: 65536, MaxOutputTokens: 32768},
+		QWEN3_235B_A22B:  {Name: QWEN3_235B_A22B, ContextWindow: 131072, MaxOutputTokens: 8192},
+		QWEN3_32B:        {Name: QWEN3_32B, ContextWindow: 131072, MaxOutputTokens: 8192},
+		QWEB3_30B_A3B:    {Name: QWEB3_30B_A3B, ContextWindow: 131072, MaxOutputTokens: 8192},
+		QWEN3_14B:        {Name: QWEN3_14B, ContextWindow: 131072, MaxOutputTokens: 8192},
+		QWEN3_8B:         {Name: QWEN3_8B, ContextWindow: 131072, MaxOutputTokens: 8192},
+		QWEN3_4B:         {Name: QWEN3_4B, ContextWindow: 131072, MaxOutputTokens: 8192},
+		QWEN3_1_7B:       {Name: QWEN3_1_7B, ContextWindow: 32768, MaxOutputTokens: 8192},
+		QWEN3_0_6B:       {Name: QWEN3_0_6B, ContextWindow: 32768, MaxOutputTokens: 8192},
+		QWen2_5_7b:       {Name: QWen2_5_7b, ContextWindow: 32768, MaxOutputTokens: 8192},
+		QwQ_plus:         {Name: QwQ_plus, ContextWindow: 131072, MaxOutputTokens: 8192},
+		QwQ_plus_latest:  {Name: QwQ_plus_latest, ContextWindow: 131072, MaxOutputTokens: 8192},
+		QwQ_32b:          {Name: QwQ_32b, ContextWindow: 131072, MaxOutputTokens: 8192},
+	}
 )
+
+// RegisterModel adds or replaces the limits known for a model.
+func RegisterModel(info ModelInfo) {
+	modelRegistryMu.Lock()
+	defer modelRegistryMu.Unlock()
+	modelRegistry[info.Name] = info
+}
+
+// LookupModel returns the limits known for a model.
+func LookupModel(name string) (ModelInfo, bool) {
+	modelRegistryMu.RLock()
+	defer modelRegistryMu.RUnlock()
+	info, ok := modelRegistry[name]
+	return info, ok
+}
@@ -5,7 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
-	"strconv"
+	"time"
 
 	deepseek "github.com/p9966/go-deepseek/internal"
 )
@@ -18,15 +18,18 @@ type OllamaChatRequest struct {
 	Tools     []Tools             `json:"tools,omitempty"`      // Optional: the tools to use for the chat
 	Format    map[string]any      `json:"format,omitempty"`     // Optional: the format to return a response in. Format can be json or a JSON schema
 	Stream    bool                `json:"stream"`               // Optional: if false the response will be returned as a single response object, rather than a stream of objects
+	Think     *bool               `json:"think,omitempty"`      // Optional: for thinking models, whether the model should think before responding; nil uses the model default
 	Options   *Options            `json:"options,omitempty"`    // Optional: additional model parameters listed in the documentation for the Modelfile such as temperature, https://github.com/ollama/ollama/blob/main/docs/modelfile.md#valid-parameters-and-values
-	KeepAlive int                 `json:"keep_alive,omitempty"` // Optional: controls how long the model will stay loaded into memory following the request (default: 5m)
+	KeepAlive *KeepAlive          `json:"keep_alive,omitempty"` // Optional: controls how long the model will stay loaded into memory following the request (default: 5m); KeepAliveFor(0) unloads it immediately, KeepAliveForever() keeps it loaded
 }
 
 type OllamaChatMessage struct {
 	Role      string       `json:"role"`
 	Content   string       `json:"content"`
+	Thinking  string       `json:"thinking,omitempty"` // the model's thinking process, for thinking models
 	Images    []string     `json:"images,omitempty"`
 	ToolCalls []OllamaTool `json:"tool_calls,omitempty"`
+	ToolName  string       `json:"tool_name,omitempty"` // the name of the tool a "tool" message answers
 }
 
 type OllamaTool struct {
@@ -60,6 +63,7 @@ func (c *Client) CreateOllamaChatCompletion(ctx context.Context, req *OllamaChat
 		return nil, err
 	}
 
+	start := time.Now()
 	resp, err := c.Do(request)
 	if err != nil {
 		return nil, err
@@ -67,13 +71,17 @@ func (c *Client) CreateOllamaChatCompletion(ctx context.Context, req *OllamaChat
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("unexpected status code: " + strconv.Itoa(resp.StatusCode))
+		return nil, newAPIError(resp)
 	}
 
 	var generateResp OllamaChatResponse
 	if err := json.NewDecoder(resp.Body).Decode(&generateResp); err != nil {
 		return nil, err
 	}
+	if msg := generateResp.Message; msg != nil && msg.Thinking == "" {
+		msg.Thinking, msg.Content = splitInlineThinking(msg.Content)
+	}
+	c.recordUsage(ctx, ollamaChatCompletionSuffix, req.Model, ollamaUsage(&generateResp), start)
 
 	return &generateResp, nil
 }
@@ -5,7 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
-	"strconv"
+	"time"
 
 	deepseek "github.com/p9966/go-deepseek/internal"
 )
@@ -13,11 +13,11 @@ import (
 var ollamaEmbedSuffix = "/api/embed"
 
 type OllamaEmbedRequest struct {
-	Model     string   `json:"model"`                // name of model to generate embeddings from
-	Input     any      `json:"input"`                // text or list of text to generate embeddings for
-	Truncate  bool     `json:"truncate,omitempty"`   // Optional: truncates the end of each input to fit within context length. Returns error if false and context length is exceeded. Defaults to true
-	Options   *Options `json:"options,omitempty"`    // Optional: additional model parameters listed in the documentation for the Modelfile such as temperature, https://github.com/ollama/ollama/blob/main/docs/modelfile.md#valid-parameters-and-values
-	KeepAlive int      `json:"keep_alive,omitempty"` // Optional: controls how long the model will stay loaded into memory following the request (default: 5m)
+	Model     string     `json:"model"`                // name of model to generate embeddings from
+	Input     any        `json:"input"`                // text or list of text to generate embeddings for
+	Truncate  bool       `json:"truncate,omitempty"`   // Optional: truncates the end of each input to fit within context length. Returns error if false and context length is exceeded. Defaults to true
+	Options   *Options   `json:"options,omitempty"`    // Optional: additional model parameters listed in the documentation for the Modelfile such as temperature, https://github.com/ollama/ollama/blob/main/docs/modelfile.md#valid-parameters-and-values
+	KeepAlive *KeepAlive `json:"keep_alive,omitempty"` // Optional: controls how long the model will stay loaded into memory following the request (default: 5m); KeepAliveFor(0) unloads it immediately, KeepAliveForever() keeps it loaded
 }
 
 type OllamaEmbedResponse struct {
@@ -38,6 +38,7 @@ func (c *Client) CreateOllamaEmbed(ctx context.Context, req *OllamaEmbedRequest)
 		return nil, err
 	}
 
+	start := time.Now()
 	resp, err := c.Do(request)
 	if err != nil {
 		return nil, err
@@ -45,12 +46,13 @@ func (c *Client) CreateOllamaEmbed(ctx context.Context, req *OllamaEmbedRequest)
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("unexpected status code: " + strconv.Itoa(resp.StatusCode))
+		return nil, newAPIError(resp)
 	}
 
 	var embedResp OllamaEmbedResponse
 	if err := json.NewDecoder(resp.Body).Decode(&embedResp); err != nil {
 		return nil, err
 	}
+	c.recordUsage(ctx, ollamaEmbedSuffix, req.Model, ollamaCountUsage(embedResp.PromptEvalCount, 0), start)
 	return &embedResp, nil
 }
@@ -5,7 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
-	"strconv"
+	"time"
 
 	deepseek "github.com/p9966/go-deepseek/internal"
 )
@@ -13,17 +13,19 @@ import (
 var ollamaGenerateSuffix = "/api/generate"
 
 type OllamaGenerateRequest struct {
-	Model     string   `json:"model"`
-	Prompt    string   `json:"prompt"`               // Optional: the prompt to generate a response for
-	Stream    bool     `json:"stream"`               // Optional: if false the response will be returned as a single response object, rather than a stream of objects
-	Suffix    string   `json:"suffix,omitempty"`     // Optional: the text after the model response
-	Images    []string `json:"images,omitempty"`     // Optional: a list of base64-encoded images (for multimodal models such as llava)
-	Format    any      `json:"format,omitempty"`     // Optional: the format to return a response in. Format can be json or a JSON schema
-	Options   *Options `json:"options,omitempty"`    // Optional: additional model parameters listed in the documentation for the Modelfile such as temperature, https://github.com/ollama/ollama/blob/main/docs/modelfile.md#valid-parameters-and-values
-	System    string   `json:"system,omitempty"`     // Optional: system message to (overrides what is defined in the Modelfile, https://github.com/ollama/ollama/blob/main/docs/modelfile.md#valid-parameters-and-values)
-	Template  string   `json:"template,omitempty"`   // Optional: the prompt template to use (overrides what is defined in the Modelfile, https://github.com/ollama/ollama/blob/main/docs/modelfile.md#valid-parameters-and-values)
-	Raw       bool     `json:"raw,omitempty"`        // Optional: if true no formatting will be applied to the prompt. You may choose to use the raw parameter if you are specifying a full templated prompt in your request to the API
-	KeepAlive int      `json:"keep_alive,omitempty"` // Optional: controls how long the model will stay loaded into memory following the request (default: 5m)
+	Model     string     `json:"model"`
+	Prompt    string     `json:"prompt"`               // Optional: the prompt to generate a response for
+	Stream    bool       `json:"stream"`               // Optional: if false the response will be returned as a single response object, rather than a stream of objects
+	Think     *bool      `json:"think,omitempty"`      // Optional: for thinking models, whether the model should think before responding; nil uses the model default
+	Suffix    string     `json:"suffix,omitempty"`     // Optional: the text after the model response
+	Images    []string   `json:"images,omitempty"`     // Optional: a list of base64-encoded images (for multimodal models such as llava)
+	Format    any        `json:"format,omitempty"`     // Optional: the format to return a response in. Format can be json or a JSON schema
+	Options   *Options   `json:"options,omitempty"`    // Optional: additional model parameters listed in the documentation for the Modelfile such as temperature, https://github.com/ollama/ollama/blob/main/docs/modelfile.md#valid-parameters-and-values
+	System    string     `json:"system,omitempty"`     // Optional: system message to (overrides what is defined in the Modelfile, https://github.com/ollama/ollama/blob/main/docs/modelfile.md#valid-parameters-and-values)
+	Template  string     `json:"template,omitempty"`   // Optional: the prompt template to use (overrides what is defined in the Modelfile, https://github.com/ollama/ollama/blob/main/docs/modelfile.md#valid-parameters-and-values)
+	Context   []int      `json:"context,omitempty"`    // Optional: the context returned by a previous response, used to keep a short conversational memory
+	Raw       bool       `json:"raw,omitempty"`        // Optional: if true no formatting will be applied to the prompt. You may choose to use the raw parameter if you are specifying a full templated prompt in your request to the API
+	KeepAlive *KeepAlive `json:"keep_alive,omitempty"` // Optional: controls how long the model will stay loaded into memory following the request (default: 5m); KeepAliveFor(0) unloads it immediately, KeepAliveForever() keeps it loaded
 }
 
 type Options struct {
@@ -59,7 +61,8 @@ type Options struct {
 type OllamaGenerateResponse struct {
 	Model              string `json:"model"`
 	CreatedAt          string `json:"created_at"`
-	Response           string `json:"response"` // empty if the response was streamed, if not streamed, this will contain the full response
+	Response           string `json:"response"`           // empty if the response was streamed, if not streamed, this will contain the full response
+	Thinking           string `json:"thinking,omitempty"` // the model's thinking process, for thinking models
 	Done               bool   `json:"done"`
 	Context            []int  `json:"context,omitempty"`              // an encoding of the conversation used in this response, this can be sent in the next request to keep a conversational memory
 	TotalDuration      int64  `json:"total_duration,omitempty"`       // time spent generating the response
@@ -80,6 +83,7 @@ func (c *Client) CreateOllamaGenerate(ctx context.Context, req *OllamaGenerateRe
 		return nil, err
 	}
 
+	start := time.Now()
 	resp, err := c.Do(request)
 	if err != nil {
 		return nil, err
@@ -87,13 +91,17 @@ func (c *Client) CreateOllamaGenerate(ctx context.Context, req *OllamaGenerateRe
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("unexpected status code: " + strconv.Itoa(resp.StatusCode))
+		return nil, newAPIError(resp)
 	}
 
 	var generateResp OllamaGenerateResponse
 	if err := json.NewDecoder(resp.Body).Decode(&generateResp); err != nil {
 		return nil, err
 	}
+	if generateResp.Thinking == "" {
+		generateResp.Thinking, generateResp.Response = splitInlineThinking(generateResp.Response)
+	}
+	c.recordUsage(ctx, ollamaGenerateSuffix, req.Model, ollamaCountUsage(generateResp.PromptEvalCount, generateResp.EvalCount), start)
 
 	return &generateResp, nil
 }
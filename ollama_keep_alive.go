@@ -0,0 +1,114 @@
+package deepseek
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KeepAlive controls how long Ollama keeps a model loaded after a request. A
+// nil *KeepAlive leaves the server default (5m) in place.
+type KeepAlive struct {
+	d time.Duration
+}
+
+// KeepAliveFor keeps the model loaded for d. Zero unloads the model as soon as
+// the request completes and a negative duration keeps it loaded forever.
+func KeepAliveFor(d time.Duration) *KeepAlive {
+	return &KeepAlive{d: d}
+}
+
+// KeepAliveForever keeps the model loaded until it is unloaded explicitly.
+func KeepAliveForever() *KeepAlive {
+	return &KeepAlive{d: -1}
+}
+
+// ParseKeepAlive parses a keep_alive value as accepted by Ollama: a duration
+// string such as "10m" or "-1", or a number of seconds.
+func ParseKeepAlive(s string) (*KeepAlive, error) {
+	s = strings.TrimSpace(s)
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		return secondsKeepAlive(seconds), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return nil, errors.New("invalid keep_alive: " + s)
+	}
+	if d < 0 {
+		return KeepAliveForever(), nil
+	}
+	return KeepAliveFor(d), nil
+}
+
+func secondsKeepAlive(seconds float64) *KeepAlive {
+	if seconds < 0 {
+		return KeepAliveForever()
+	}
+	return KeepAliveFor(time.Duration(seconds * float64(time.Second)))
+}
+
+// Duration returns the keep alive duration, negative meaning forever.
+func (k KeepAlive) Duration() time.Duration {
+	return k.d
+}
+
+// Forever reports whether the model is kept loaded indefinitely.
+func (k KeepAlive) Forever() bool {
+	return k.d < 0
+}
+
+func (k KeepAlive) String() string {
+	switch {
+	case k.d < 0:
+		return "-1"
+	case k.d == 0:
+		return "0"
+	}
+	return k.d.String()
+}
+
+func (k KeepAlive) MarshalJSON() ([]byte, error) {
+	switch {
+	case k.d < 0:
+		return []byte("-1"), nil
+	case k.d == 0:
+		return []byte("0"), nil
+	}
+	return json.Marshal(k.d.String())
+}
+
+func (k *KeepAlive) UnmarshalJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case float64:
+		*k = *secondsKeepAlive(v)
+	case string:
+		parsed, err := ParseKeepAlive(v)
+		if err != nil {
+			return err
+		}
+		*k = *parsed
+	default:
+		return errors.New("invalid keep_alive: " + string(data))
+	}
+	return nil
+}
+
+// LoadOllamaModel loads model into memory without generating anything, and
+// keeps it loaded for keepAlive (nil for the server default).
+func (c *Client) LoadOllamaModel(ctx context.Context, model string, keepAlive *KeepAlive) error {
+	_, err := c.CreateOllamaGenerate(ctx, &OllamaGenerateRequest{Model: model, KeepAlive: keepAlive})
+	return err
+}
+
+// UnloadOllamaModel evicts model from memory immediately.
+func (c *Client) UnloadOllamaModel(ctx context.Context, model string) error {
+	_, err := c.CreateOllamaGenerate(ctx, &OllamaGenerateRequest{Model: model, KeepAlive: KeepAliveFor(0)})
+	return err
+}
@@ -0,0 +1,38 @@
+package deepseek
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestKeepAliveJSON(t *testing.T) {
+	tests := []struct {
+		req  OllamaGenerateRequest
+		want string
+	}{
+		{OllamaGenerateRequest{Model: "m"}, `{"model":"m","prompt":"","stream":false}`},
+		{OllamaGenerateRequest{Model: "m", KeepAlive: KeepAliveFor(0)}, `{"model":"m","prompt":"","stream":false,"keep_alive":0}`},
+		{OllamaGenerateRequest{Model: "m", KeepAlive: KeepAliveForever()}, `{"model":"m","prompt":"","stream":false,"keep_alive":-1}`},
+		{OllamaGenerateRequest{Model: "m", KeepAlive: KeepAliveFor(10 * time.Minute)}, `{"model":"m","prompt":"","stream":false,"keep_alive":"10m0s"}`},
+	}
+	for _, tt := range tests {
+		buf, err := json.Marshal(tt.req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != tt.want {
+			t.Errorf("got %s, want %s", buf, tt.want)
+		}
+	}
+
+	for input, want := range map[string]time.Duration{`"10m"`: 10 * time.Minute, `300`: 5 * time.Minute, `"-1"`: -1, `-1`: -1} {
+		var k KeepAlive
+		if err := json.Unmarshal([]byte(input), &k); err != nil {
+			t.Fatal(err)
+		}
+		if k.Duration() != want {
+			t.Errorf("%s decoded to %v, want %v", input, k.Duration(), want)
+		}
+	}
+}
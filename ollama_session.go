@@ -0,0 +1,160 @@
+package deepseek
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// GenerateSession threads the context returned by CreateOllamaGenerate into
+// the next request, giving /api/generate a conversational memory. It is safe
+// for concurrent use, although calls are serialized.
+type GenerateSession struct {
+	Client    *Client
+	Model     string
+	System    string     // Optional: system message, overrides the Modelfile
+	Options   *Options   // Optional: model parameters
+	KeepAlive *KeepAlive // Optional: how long the model stays loaded
+
+	mu      sync.Mutex
+	context []int
+}
+
+// NewGenerateSession creates a session for model.
+func NewGenerateSession(client *Client, model string) *GenerateSession {
+	return &GenerateSession{Client: client, Model: model}
+}
+
+// Generate sends prompt with the context of the previous call and keeps the
+// returned context for the next one.
+func (s *GenerateSession) Generate(ctx context.Context, prompt string) (*OllamaGenerateResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp, err := s.Client.CreateOllamaGenerate(ctx, &OllamaGenerateRequest{
+		Model:     s.Model,
+		Prompt:    prompt,
+		System:    s.System,
+		Options:   s.Options,
+		KeepAlive: s.KeepAlive,
+		Context:   s.context,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Context) > 0 {
+		s.context = resp.Context
+	}
+	return resp, nil
+}
+
+// Context returns the context that will be sent with the next call.
+func (s *GenerateSession) Context() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int(nil), s.context...)
+}
+
+// SetContext replaces the context, e.g. to resume a saved session.
+func (s *GenerateSession) SetContext(tokens []int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.context = append([]int(nil), tokens...)
+}
+
+// Reset forgets the conversation.
+func (s *GenerateSession) Reset() {
+	s.SetContext(nil)
+}
+
+// ChatTemplate renders chat messages into a raw prompt that mirrors a model's
+// chat template, so base models can be driven through /api/generate with Raw
+// set. Templates receive .System, the joined system messages, and .Messages,
+// the remaining messages.
+type ChatTemplate struct {
+	tmpl *template.Template
+	Stop []string // Stop sequences ending an assistant turn
+}
+
+// NewChatTemplate parses a Go text/template into a ChatTemplate.
+func NewChatTemplate(name, text string, stop ...string) (*ChatTemplate, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &ChatTemplate{tmpl: tmpl, Stop: stop}, nil
+}
+
+// MustChatTemplate is like NewChatTemplate but panics if the template cannot
+// be parsed.
+func MustChatTemplate(name, text string, stop ...string) *ChatTemplate {
+	t, err := NewChatTemplate(name, text, stop...)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+var (
+	// ChatMLTemplate is the chat format of Qwen and QwQ models.
+	ChatMLTemplate = MustChatTemplate("chatml", `{{if .System}}<|im_start|>system
+{{.System}}<|im_end|>
+{{end}}{{range .Messages}}<|im_start|>{{.Role}}
+{{.Content}}<|im_end|>
+{{end}}<|im_start|>assistant
+`, "<|im_end|>")
+
+	// Llama3Template is the chat format of Llama 3 models.
+	Llama3Template = MustChatTemplate("llama3", `<|begin_of_text|>{{if .System}}<|start_header_id|>system<|end_header_id|>
+
+{{.System}}<|eot_id|>{{end}}{{range .Messages}}<|start_header_id|>{{.Role}}<|end_header_id|>
+
+{{.Content}}<|eot_id|>{{end}}<|start_header_id|>assistant<|end_header_id|>
+
+`, "<|eot_id|>")
+
+	// DeepSeekTemplate is the chat format of DeepSeek V3 and R1 models.
+	DeepSeekTemplate = MustChatTemplate("deepseek", `<｜begin▁of▁sentence｜>{{.System}}{{range .Messages}}{{if eq .Role "assistant"}}<｜Assistant｜>{{.Content}}<｜end▁of▁sentence｜>{{else}}<｜User｜>{{.Content}}{{end}}{{end}}<｜Assistant｜>`,
+		"<｜end▁of▁sentence｜>")
+)
+
+// Render renders messages into a raw prompt.
+func (t *ChatTemplate) Render(messages []OllamaChatMessage) (string, error) {
+	var system []string
+	var rest []OllamaChatMessage
+	for _, msg := range messages {
+		if msg.Role == ChatMessageRoleSystem {
+			system = append(system, msg.Content)
+			continue
+		}
+		rest = append(rest, msg)
+	}
+	var b strings.Builder
+	err := t.tmpl.Execute(&b, struct {
+		System   string
+		Messages []OllamaChatMessage
+	}{System: strings.Join(system, "\n\n"), Messages: rest})
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// GenerateRequest renders messages and returns a raw generate request for
+// model that stops at the end of the assistant turn.
+func (t *ChatTemplate) GenerateRequest(model string, messages []OllamaChatMessage) (*OllamaGenerateRequest, error) {
+	if len(messages) == 0 {
+		return nil, errors.New("messages can not be empty")
+	}
+	prompt, err := t.Render(messages)
+	if err != nil {
+		return nil, err
+	}
+	return &OllamaGenerateRequest{
+		Model:   model,
+		Prompt:  prompt,
+		Raw:     true,
+		Options: &Options{Stop: t.Stop},
+	}, nil
+}
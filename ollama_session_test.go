@@ -0,0 +1,59 @@
+package deepseek
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGenerateSessionThreadsContext(t *testing.T) {
+	var received [][]int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OllamaGenerateRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		received = append(received, req.Context)
+		json.NewEncoder(w).Encode(OllamaGenerateResponse{Done: true, Context: append(req.Context, len(received))})
+	}))
+	defer server.Close()
+
+	session := NewGenerateSession(&Client{BaseUrl: server.URL}, QWen2_5_7b)
+	for i := 0; i < 3; i++ {
+		if _, err := session.Generate(context.Background(), "hi"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := [][]int{nil, {1}, {1, 2}}; !reflect.DeepEqual(received, want) {
+		t.Fatalf("contexts sent = %v, want %v", received, want)
+	}
+}
+
+func TestChatTemplateRender(t *testing.T) {
+	req, err := ChatMLTemplate.GenerateRequest(QWen2_5_7b, []OllamaChatMessage{
+		{Role: ChatMessageRoleSystem, Content: "Be brief."},
+		{Role: ChatMessageRoleUser, Content: "Hi"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<|im_start|>system\nBe brief.<|im_end|>\n<|im_start|>user\nHi<|im_end|>\n<|im_start|>assistant\n"
+	if !req.Raw || req.Prompt != want || req.Options.Stop[0] != "<|im_end|>" {
+		t.Fatalf("unexpected raw request: %+v", req)
+	}
+}
+
+func TestDeepSeekTemplateRender(t *testing.T) {
+	req, err := DeepSeekTemplate.GenerateRequest("deepseek-r1:7b", []OllamaChatMessage{
+		{Role: ChatMessageRoleSystem, Content: "Be brief."},
+		{Role: ChatMessageRoleUser, Content: "Hi"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<｜begin▁of▁sentence｜>Be brief.<｜User｜>Hi<｜Assistant｜>"
+	if req.Prompt != want {
+		t.Fatalf("prompt = %q", req.Prompt)
+	}
+}
@@ -0,0 +1,232 @@
+package deepseek
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	deepseek "github.com/p9966/go-deepseek/internal"
+)
+
+type OllamaChatStream interface {
+	Recv() (*OllamaChatResponse, error)
+	Close() error
+}
+
+type OllamaGenerateStream interface {
+	Recv() (*OllamaGenerateResponse, error)
+	Close() error
+}
+
+// ollamaStream reads the newline delimited JSON objects streamed by Ollama.
+type ollamaStream struct {
+	ctx      context.Context
+	client   *Client
+	endpoint string
+	model    string
+	start    time.Time
+	resp     *http.Response
+	reader   *bufio.Reader
+	think    bool // whether the request enabled thinking
+	splitter ThinkSplitter
+	head     string // leading content held back while it may still be a think tag
+	started  bool   // whether the splitter knows if the output starts in a think block
+	done     bool
+	err      error // returned once the content held back has been flushed
+}
+
+func (s *ollamaStream) next(v any) error {
+	if s.err != nil {
+		return s.err
+	}
+	if s.done {
+		return io.EOF
+	}
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || strings.TrimSpace(line) == "") {
+			if errors.Is(err, io.EOF) {
+				return io.EOF
+			}
+			return fmt.Errorf("failed to read stream: %w", err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		var streamErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal([]byte(line), &streamErr) == nil && streamErr.Error != "" {
+			return errors.New("ollama stream error: " + streamErr.Error)
+		}
+		if err := json.Unmarshal([]byte(line), v); err != nil {
+			return fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		return nil
+	}
+}
+
+// split moves inline <think> text of a chunk into thinking. Chunks that already
+// carry a separate thinking field are left untouched.
+//
+// Only a possible tag prefix is held back. The output is taken to start inside
+// a think block opened by the prompt template when it starts with a closing
+// tag, or when the request enabled thinking and it does not start with an
+// opening tag.
+func (s *ollamaStream) split(thinking, content *string, done bool) {
+	if *thinking != "" {
+		if !s.started {
+			s.started = true
+			*content, s.head = s.head+*content, ""
+		}
+		return
+	}
+	if !s.started {
+		s.head += *content
+		lead := strings.TrimLeft(s.head, " \t\r\n")
+		if !done && (lead == "" || isTagPrefix(lead, thinkOpenTag) || isTagPrefix(lead, thinkCloseTag)) {
+			*content = ""
+			return
+		}
+		s.started = true
+		s.splitter.StartInThink = strings.HasPrefix(lead, thinkCloseTag) || s.think && !strings.HasPrefix(lead, thinkOpenTag)
+		*content, s.head = s.head, ""
+	}
+	t, a := s.splitter.Push(*content)
+	if done {
+		t2, a2 := s.splitter.Flush()
+		t, a = t+t2, a+a2
+		s.done = true
+	}
+	*thinking, *content = t, a
+}
+
+// flush records err to be returned by the next call to next and returns the
+// text still held back, so a stream cut short does not lose it.
+func (s *ollamaStream) flush(err error) (thinking, content string) {
+	s.err = err
+	if s.done {
+		return "", ""
+	}
+	s.split(&thinking, &content, true)
+	return thinking, content
+}
+
+// isTagPrefix reports whether text is a proper prefix of tag.
+func isTagPrefix(text, tag string) bool {
+	return len(text) < len(tag) && strings.HasPrefix(tag, text)
+}
+
+// record adds the usage reported by the final chunk to the client's ledger.
+func (s *ollamaStream) record(promptEvalCount, evalCount int) {
+	s.client.recordUsage(s.ctx, s.endpoint, s.model, ollamaCountUsage(promptEvalCount, evalCount), s.start)
+}
+
+func (s *ollamaStream) Close() error {
+	return s.resp.Body.Close()
+}
+
+type ollamaChatStream struct {
+	ollamaStream
+}
+
+func (s *ollamaChatStream) Recv() (*OllamaChatResponse, error) {
+	var resp OllamaChatResponse
+	if err := s.next(&resp); err != nil {
+		thinking, content := s.flush(err)
+		if thinking == "" && content == "" {
+			return nil, err
+		}
+		return &OllamaChatResponse{Model: s.model, Message: &OllamaChatMessage{Role: ChatMessageRoleAssistant, Thinking: thinking, Content: content}}, nil
+	}
+	if resp.Message == nil {
+		resp.Message = &OllamaChatMessage{Role: ChatMessageRoleAssistant}
+	}
+	s.split(&resp.Message.Thinking, &resp.Message.Content, resp.Done)
+	if resp.Done {
+		s.record(resp.PromptEvalCount, resp.EvalCount)
+	}
+	return &resp, nil
+}
+
+type ollamaGenerateStream struct {
+	ollamaStream
+}
+
+func (s *ollamaGenerateStream) Recv() (*OllamaGenerateResponse, error) {
+	var resp OllamaGenerateResponse
+	if err := s.next(&resp); err != nil {
+		thinking, content := s.flush(err)
+		if thinking == "" && content == "" {
+			return nil, err
+		}
+		return &OllamaGenerateResponse{Model: s.model, Thinking: thinking, Response: content}, nil
+	}
+	s.split(&resp.Thinking, &resp.Response, resp.Done)
+	if resp.Done {
+		s.record(resp.PromptEvalCount, resp.EvalCount)
+	}
+	return &resp, nil
+}
+
+// CreateOllamaChatCompletionStream streams a chat completion from Ollama.
+// Inline <think> blocks are moved into Message.Thinking.
+func (c *Client) CreateOllamaChatCompletionStream(ctx context.Context, req *OllamaChatRequest) (OllamaChatStream, error) {
+	if req == nil {
+		return nil, errors.New("request can not be nil")
+	}
+	streamReq := *req
+	streamReq.Stream = true
+	start := time.Now()
+	resp, err := c.openOllamaStream(ctx, ollamaChatCompletionSuffix, &streamReq)
+	if err != nil {
+		return nil, err
+	}
+	return &ollamaChatStream{c.newOllamaStream(ctx, ollamaChatCompletionSuffix, req.Model, req.Think, start, resp)}, nil
+}
+
+// CreateOllamaGenerateStream streams a completion from Ollama. Inline <think>
+// blocks are moved into Thinking.
+func (c *Client) CreateOllamaGenerateStream(ctx context.Context, req *OllamaGenerateRequest) (OllamaGenerateStream, error) {
+	if req == nil {
+		return nil, errors.New("request can not be nil")
+	}
+	streamReq := *req
+	streamReq.Stream = true
+	start := time.Now()
+	resp, err := c.openOllamaStream(ctx, ollamaGenerateSuffix, &streamReq)
+	if err != nil {
+		return nil, err
+	}
+	return &ollamaGenerateStream{c.newOllamaStream(ctx, ollamaGenerateSuffix, req.Model, req.Think, start, resp)}, nil
+}
+
+func (c *Client) newOllamaStream(ctx context.Context, endpoint, model string, think *bool, start time.Time, resp *http.Response) ollamaStream {
+	return ollamaStream{ctx: ctx, client: c, endpoint: endpoint, model: model, think: think != nil && *think, start: start, resp: resp, reader: bufio.NewReader(resp.Body)}
+}
+
+func (c *Client) openOllamaStream(ctx context.Context, path string, body any) (*http.Response, error) {
+	request, err := deepseek.NewRequestBuilder(c.AuthToken).SetMethod(http.MethodPost).SetBaseUrl(c.BaseUrl).SetPath(path).SetBody(body).Build(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	resp, err := c.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, newAPIError(resp)
+	}
+	return resp, nil
+}
@@ -0,0 +1,95 @@
+package deepseek
+
+import "strings"
+
+const (
+	thinkOpenTag  = "<think>"
+	thinkCloseTag = "</think>"
+)
+
+// SplitThinking separates inline <think>...</think> blocks from content, as
+// emitted by reasoning models that do not use the separate thinking field. A
+// closing tag without an opening tag marks everything before it as thinking,
+// which happens when the chat template opens the block in the prompt.
+func SplitThinking(content string) (thinking, answer string) {
+	openIdx, closeIdx := strings.Index(content, thinkOpenTag), strings.Index(content, thinkCloseTag)
+	s := ThinkSplitter{StartInThink: closeIdx >= 0 && (openIdx < 0 || closeIdx < openIdx)}
+	t1, a1 := s.Push(content)
+	t2, a2 := s.Flush()
+	return strings.TrimSpace(t1 + t2), strings.TrimSpace(a1 + a2)
+}
+
+// splitInlineThinking is SplitThinking for responses that may not contain any
+// tags, in which case content is returned unchanged.
+func splitInlineThinking(content string) (thinking, answer string) {
+	if !strings.Contains(content, thinkCloseTag) {
+		return "", content
+	}
+	return SplitThinking(content)
+}
+
+// ThinkSplitter separates inline <think> blocks from streamed content whose
+// tags may be split across chunks. The zero value is ready to use.
+type ThinkSplitter struct {
+	StartInThink bool // The output starts inside a think block opened by the prompt template
+
+	started bool
+	inThink bool
+	pending string
+}
+
+// Push consumes a chunk and returns the thinking and answer text that can be
+// emitted so far.
+func (s *ThinkSplitter) Push(chunk string) (thinking, answer string) {
+	if !s.started {
+		s.started = true
+		s.inThink = s.StartInThink
+	}
+	text := s.pending + chunk
+	s.pending = ""
+	var think, out strings.Builder
+	for text != "" {
+		tag := thinkOpenTag
+		if s.inThink {
+			tag = thinkCloseTag
+		}
+		idx := strings.Index(text, tag)
+		if idx < 0 {
+			keep := partialSuffix(text, tag)
+			s.write(&think, &out, text[:len(text)-keep])
+			s.pending = text[len(text)-keep:]
+			break
+		}
+		s.write(&think, &out, text[:idx])
+		s.inThink = !s.inThink
+		text = text[idx+len(tag):]
+	}
+	return think.String(), out.String()
+}
+
+// Flush returns the text held back while waiting for a tag to complete.
+func (s *ThinkSplitter) Flush() (thinking, answer string) {
+	var think, out strings.Builder
+	s.write(&think, &out, s.pending)
+	s.pending = ""
+	return think.String(), out.String()
+}
+
+func (s *ThinkSplitter) write(think, out *strings.Builder, text string) {
+	if s.inThink {
+		think.WriteString(text)
+	} else {
+		out.WriteString(text)
+	}
+}
+
+// partialSuffix returns the length of the longest suffix of text that is a
+// proper prefix of tag.
+func partialSuffix(text, tag string) int {
+	for k := min(len(tag)-1, len(text)); k > 0; k-- {
+		if strings.HasSuffix(text, tag[:k]) {
+			return k
+		}
+	}
+	return 0
+}
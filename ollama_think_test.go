@@ -0,0 +1,111 @@
+package deepseek
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSplitThinking(t *testing.T) {
+	tests := []struct {
+		content, thinking, answer string
+	}{
+		{"<think>\nLet me see.\n</think>\n\nHello!", "Let me see.", "Hello!"},
+		{"Okay, the user greets me.\n</think>\nHi", "Okay, the user greets me.", "Hi"},
+		{"No reasoning here", "", "No reasoning here"},
+	}
+	for _, tt := range tests {
+		thinking, answer := SplitThinking(tt.content)
+		if thinking != tt.thinking || answer != tt.answer {
+			t.Errorf("SplitThinking(%q) = %q, %q", tt.content, thinking, answer)
+		}
+	}
+}
+
+func TestOllamaChatStreamSplitsThinking(t *testing.T) {
+	tests := []struct {
+		chunks            []string
+		think             bool
+		thinking, content string
+	}{
+		{[]string{"<thi", "nk>plan", "</th", "ink>ans", "wer"}, false, "plan", "answer"},
+		// The template opened the block in the prompt.
+		{[]string{"pl", "an</th", "ink>ans", "wer"}, true, "plan", "answer"},
+		{[]string{"</th", "ink>ans", "wer"}, false, "", "answer"},
+		{[]string{"no ", "tags"}, false, "", "no tags"},
+		{[]string{"a < b", " </thi"}, false, "", "a < b </thi"},
+	}
+	for _, tt := range tests {
+		responses := streamOllamaChat(t, tt.chunks, tt.think, true)
+		thinking, content := joinOllamaChat(responses)
+		if thinking != tt.thinking || content != tt.content {
+			t.Errorf("chunks %q: thinking = %q, content = %q", tt.chunks, thinking, content)
+		}
+	}
+}
+
+func TestOllamaChatStreamDoesNotHoldTaglessContent(t *testing.T) {
+	responses := streamOllamaChat(t, []string{"Hello", " world"}, false, true)
+	if len(responses) != 2 {
+		t.Fatalf("got %d responses, want 2", len(responses))
+	}
+	if first := responses[0]; first.Done || first.Message.Content != "Hello" {
+		t.Errorf("first response = done %v, content %q, want content before done", first.Done, first.Message.Content)
+	}
+}
+
+func TestOllamaChatStreamFlushesWithoutDone(t *testing.T) {
+	responses := streamOllamaChat(t, []string{"<think>plan</th"}, false, false)
+	if thinking, content := joinOllamaChat(responses); thinking != "plan</th" || content != "" {
+		t.Errorf("thinking = %q, content = %q", thinking, content)
+	}
+}
+
+// streamOllamaChat streams chunks as Ollama chat responses, marking the last
+// one done if done is set, and returns the responses read from the stream.
+func streamOllamaChat(t *testing.T, chunks []string, think, done bool) []*OllamaChatResponse {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i, chunk := range chunks {
+			last := "false"
+			if done && i == len(chunks)-1 {
+				last = "true"
+			}
+			w.Write([]byte(`{"message":{"role":"assistant","content":"` + chunk + `"},"done":` + last + "}\n"))
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{BaseUrl: server.URL}
+	stream, err := client.CreateOllamaChatCompletionStream(context.Background(), &OllamaChatRequest{Model: QWEN3_8B, Think: &think})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var responses []*OllamaChatResponse
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+func joinOllamaChat(responses []*OllamaChatResponse) (string, string) {
+	var thinking, content strings.Builder
+	for _, resp := range responses {
+		thinking.WriteString(resp.Message.Thinking)
+		content.WriteString(resp.Message.Content)
+	}
+	return thinking.String(), content.String()
+}
@@ -0,0 +1,104 @@
+package deepseek
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// DiscountWindow is a daily off-peak period in UTC. Start and End are offsets
+// from UTC midnight; a window with End before Start wraps past midnight.
+type DiscountWindow struct {
+	Start    time.Duration
+	End      time.Duration
+	Discount Decimal // Fraction taken off the price, e.g. 0.5 for half price
+}
+
+func (w DiscountWindow) contains(t time.Time) bool {
+	t = t.UTC()
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.Start <= w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// ModelPricing holds the prices of a model per million tokens.
+type ModelPricing struct {
+	Model          string
+	Currency       string
+	CacheHitInput  Decimal // Price per million prompt tokens served from the context cache
+	CacheMissInput Decimal // Price per million prompt tokens not served from the cache
+	Output         Decimal // Price per million completion tokens
+	OffPeak        []DiscountWindow
+}
+
+// Cost returns the exact price of usage for a call made at t.
+func (p ModelPricing) Cost(usage Usage, at time.Time) Decimal {
+	hit, miss := usage.PromptCacheHitTokens, usage.PromptCacheMissTokens
+	if hit+miss == 0 {
+		miss = usage.PromptTokens
+	}
+	cost := p.CacheHitInput.Mul(decimalInt(hit)).
+		Add(p.CacheMissInput.Mul(decimalInt(miss))).
+		Add(p.Output.Mul(decimalInt(usage.CompletionTokens)))
+	return cost.Mul(decimalInt(1).Sub(p.discount(at))).shift(6).trim()
+}
+
+func (p ModelPricing) discount(at time.Time) Decimal {
+	for _, w := range p.OffPeak {
+		if w.contains(at) {
+			return w.Discount
+		}
+	}
+	return Decimal{}
+}
+
+// deepSeekOffPeak is the UTC 16:30-00:30 off-peak period of the DeepSeek API.
+func deepSeekOffPeak(discount string) []DiscountWindow {
+	return []DiscountWindow{{Start: 16*time.Hour + 30*time.Minute, End: 30 * time.Minute, Discount: MustParseDecimal(discount)}}
+}
+
+var (
+	pricingMu    sync.RWMutex
+	pricingTable = map[string]ModelPricing{
+		DeepSeekChat: {
+			Model: DeepSeekChat, Currency: "USD",
+			CacheHitInput:  MustParseDecimal("0.07"),
+			CacheMissInput: MustParseDecimal("0.27"),
+			Output:         MustParseDecimal("1.10"),
+			OffPeak:        deepSeekOffPeak("0.5"),
+		},
+		DeepSeekReasoner: {
+			Model: DeepSeekReasoner, Currency: "USD",
+			CacheHitInput:  MustParseDecimal("0.14"),
+			CacheMissInput: MustParseDecimal("0.55"),
+			Output:         MustParseDecimal("2.19"),
+			OffPeak:        deepSeekOffPeak("0.75"),
+		},
+	}
+)
+
+// RegisterPricing adds or replaces the prices of a model.
+func RegisterPricing(p ModelPricing) {
+	pricingMu.Lock()
+	defer pricingMu.Unlock()
+	pricingTable[p.Model] = p
+}
+
+// LookupPricing returns the prices of a model.
+func LookupPricing(model string) (ModelPricing, bool) {
+	pricingMu.RLock()
+	defer pricingMu.RUnlock()
+	p, ok := pricingTable[model]
+	return p, ok
+}
+
+// EstimateCost returns the price and currency of usage for model at t.
+func EstimateCost(model string, usage Usage, at time.Time) (Decimal, string, error) {
+	p, ok := LookupPricing(model)
+	if !ok {
+		return Decimal{}, "", errors.New("no pricing for model: " + model)
+	}
+	return p.Cost(usage, at), p.Currency, nil
+}
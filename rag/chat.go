@@ -0,0 +1,106 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	deepseek "github.com/p9966/go-deepseek"
+)
+
+// Chat is the chat backend answering questions.
+type Chat interface {
+	// Complete returns the assistant reply to messages.
+	Complete(ctx context.Context, messages []deepseek.ChatCompletionMessage) (string, error)
+	// Stream calls onDelta with each piece of the reply as it arrives and
+	// returns the whole reply.
+	Stream(ctx context.Context, messages []deepseek.ChatCompletionMessage, onDelta func(string) error) (string, error)
+}
+
+// ModelChat answers with a deepseek.ChatModel, so a Pipeline can use any
+// provider NewChatModel supports.
+type ModelChat struct {
+	Model       deepseek.ChatModel
+	Temperature float32 // Optional: sampling temperature
+	MaxTokens   int     // Optional: maximum tokens of the reply
+}
+
+func (c *ModelChat) request(messages []deepseek.ChatCompletionMessage) *deepseek.GenerateRequest {
+	return &deepseek.GenerateRequest{Messages: messages, Temperature: c.Temperature, MaxTokens: c.MaxTokens}
+}
+
+func (c *ModelChat) Complete(ctx context.Context, messages []deepseek.ChatCompletionMessage) (string, error) {
+	resp, err := c.Model.Generate(ctx, c.request(messages))
+	if err != nil {
+		return "", err
+	}
+	return resp.Message.Content, nil
+}
+
+func (c *ModelChat) Stream(ctx context.Context, messages []deepseek.ChatCompletionMessage, onDelta func(string) error) (string, error) {
+	stream, err := c.Model.Stream(ctx, c.request(messages))
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	var reply strings.Builder
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return reply.String(), nil
+		}
+		if err != nil {
+			return reply.String(), err
+		}
+		if chunk.Content == "" {
+			continue
+		}
+		reply.WriteString(chunk.Content)
+		if err := onDelta(chunk.Content); err != nil {
+			return reply.String(), err
+		}
+	}
+}
+
+// ClientChat answers with the OpenAI-style chat endpoint of DeepSeek or a
+// compatible provider.
+type ClientChat struct {
+	Client      *deepseek.Client
+	Model       string
+	Temperature float32 // Optional: sampling temperature
+	MaxTokens   int     // Optional: maximum tokens of the reply
+}
+
+func (c *ClientChat) chat() *ModelChat {
+	model := &deepseek.OpenAIChatModel{Client: c.Client, Model: c.Model}
+	return &ModelChat{Model: model, Temperature: c.Temperature, MaxTokens: c.MaxTokens}
+}
+
+func (c *ClientChat) Complete(ctx context.Context, messages []deepseek.ChatCompletionMessage) (string, error) {
+	return c.chat().Complete(ctx, messages)
+}
+
+func (c *ClientChat) Stream(ctx context.Context, messages []deepseek.ChatCompletionMessage, onDelta func(string) error) (string, error) {
+	return c.chat().Stream(ctx, messages, onDelta)
+}
+
+// OllamaChat answers with the Ollama chat endpoint.
+type OllamaChat struct {
+	Client  *deepseek.Client
+	Model   string
+	Options *deepseek.Options // Optional: model parameters
+}
+
+func (c *OllamaChat) chat() *ModelChat {
+	return &ModelChat{Model: &deepseek.OllamaChatModel{Client: c.Client, Model: c.Model, Options: c.Options}}
+}
+
+func (c *OllamaChat) Complete(ctx context.Context, messages []deepseek.ChatCompletionMessage) (string, error) {
+	return c.chat().Complete(ctx, messages)
+}
+
+func (c *OllamaChat) Stream(ctx context.Context, messages []deepseek.ChatCompletionMessage, onDelta func(string) error) (string, error) {
+	return c.chat().Stream(ctx, messages, onDelta)
+}
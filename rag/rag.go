@@ -0,0 +1,301 @@
+// Package rag answers questions from a document collection: it retrieves the
+// chunks nearest to a question, hands them to a chat model as labelled
+// sources and reports which of them the answer cites.
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	deepseek "github.com/p9966/go-deepseek"
+	"github.com/p9966/go-deepseek/textsplitter"
+	"github.com/p9966/go-deepseek/vectorstore"
+)
+
+// Metadata keys of the records written by AddDocuments.
+const (
+	MetadataText     = "text"
+	MetadataDocument = "document"
+	MetadataStart    = "start"
+	MetadataEnd      = "end"
+)
+
+// DefaultPrompt is the system prompt template. It receives .Question and
+// .Sources, the retrieved chunks.
+var DefaultPrompt = template.Must(template.New("rag").Parse(`Answer the user's question using only the sources below. After each statement, cite the sources it relies on by their ID in square brackets, for example [manual#2]. If the sources do not contain the answer, say that you do not know.
+
+Sources:
+{{range .Sources}}
+[{{.ID}}]
+{{.Text}}
+{{end}}`))
+
+// Document is a text to index.
+type Document struct {
+	ID       string
+	Text     string
+	Metadata map[string]any // Optional: copied to every chunk, e.g. a title or URL
+}
+
+// Source is a retrieved chunk.
+type Source struct {
+	ID         string
+	DocumentID string
+	Text       string
+	Start, End int // byte offsets of Text in the document
+	Score      float64
+	Metadata   map[string]any
+}
+
+// Answer is the reply to a question.
+type Answer struct {
+	Text      string
+	Citations []string // IDs of the sources cited in Text, in order of first citation
+	Sources   []Source // the sources given to the model, best first
+}
+
+// Pipeline composes an embedder, a vector store and a chat backend. Its
+// fields must not change while it is in use.
+type Pipeline struct {
+	Embedder deepseek.Embedder
+	Store    vectorstore.Index
+	Chat     Chat
+
+	Splitter         textsplitter.Splitter // Optional: chunking for AddDocuments, defaults to 1000 characters with 100 of overlap
+	Prompt           *template.Template    // Optional: system prompt template, defaults to DefaultPrompt
+	TopK             int                   // Optional: chunks retrieved per question, defaults to 5
+	MinScore         *float64              // Optional: drop chunks scoring below this
+	Filter           vectorstore.Filter    // Optional: restrict retrieval by metadata
+	MaxContextTokens int                   // Optional: token budget for the sources in the prompt, defaults to 3000
+	Counter          deepseek.TokenCounter // Optional: token estimator for the budget, defaults to the DeepSeek Tokenizer
+}
+
+// AddDocuments splits, embeds and stores documents, and returns the IDs of the
+// stored chunks. Chunk IDs are the document ID followed by "#" and the chunk
+// number. Adding a document again replaces its chunks, and chunks left over
+// from a longer earlier version are deleted.
+func (p *Pipeline) AddDocuments(ctx context.Context, docs ...Document) ([]string, error) {
+	splitter := p.Splitter
+	if splitter == nil {
+		splitter = textsplitter.Recursive{Size: 1000, Overlap: 100}
+	}
+
+	var records []vectorstore.Record
+	var texts []string
+	docIDs := make([]any, len(docs))
+	for j, doc := range docs {
+		if doc.ID == "" {
+			return nil, errors.New("document id can not be empty")
+		}
+		docIDs[j] = doc.ID
+		for i, chunk := range splitter.Split(doc.Text) {
+			metadata := make(map[string]any, len(doc.Metadata)+len(chunk.Metadata)+4)
+			for k, v := range doc.Metadata {
+				metadata[k] = v
+			}
+			for k, v := range chunk.Metadata {
+				metadata[k] = v
+			}
+			metadata[MetadataText] = chunk.Text
+			metadata[MetadataDocument] = doc.ID
+			metadata[MetadataStart] = chunk.Start
+			metadata[MetadataEnd] = chunk.End
+			records = append(records, vectorstore.Record{ID: doc.ID + "#" + strconv.Itoa(i), Metadata: metadata})
+			texts = append(texts, chunk.Text)
+		}
+	}
+	if len(records) == 0 {
+		return nil, p.deleteChunks(docIDs, nil)
+	}
+
+	vectors, err := p.Embedder.Embed(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(records) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(records), len(vectors))
+	}
+	ids := make([]string, len(records))
+	keep := make(map[string]bool, len(records))
+	for i := range records {
+		records[i].Vector = vectors[i]
+		ids[i] = records[i].ID
+		keep[ids[i]] = true
+	}
+	if err := p.Store.Upsert(records...); err != nil {
+		return nil, err
+	}
+	return ids, p.deleteChunks(docIDs, keep)
+}
+
+// deleteChunks deletes the stored chunks of the documents docIDs whose IDs
+// are not in keep.
+func (p *Pipeline) deleteChunks(docIDs []any, keep map[string]bool) error {
+	var dimension int
+	for _, id := range docIDs {
+		if r, ok := p.Store.Get(id.(string) + "#0"); ok {
+			dimension = len(r.Vector)
+			break
+		}
+	}
+	if dimension == 0 {
+		return nil
+	}
+	stored, err := p.Store.Search(vectorstore.Query{
+		Vector: make([]float64, dimension),
+		Filter: vectorstore.In(MetadataDocument, docIDs...),
+	})
+	if err != nil {
+		return err
+	}
+	var stale []string
+	for _, r := range stored {
+		if !keep[r.ID] {
+			stale = append(stale, r.ID)
+		}
+	}
+	p.Store.Delete(stale...)
+	return nil
+}
+
+// Retrieve returns the chunks nearest to question that fit the token budget,
+// best first.
+func (p *Pipeline) Retrieve(ctx context.Context, question string) ([]Source, error) {
+	vectors, err := p.Embedder.Embed(ctx, []string{question})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("expected 1 embedding, got %d", len(vectors))
+	}
+	topK := p.TopK
+	if topK <= 0 {
+		topK = 5
+	}
+	results, err := p.Store.Search(vectorstore.Query{Vector: vectors[0], TopK: topK, MinScore: p.MinScore, Filter: p.Filter})
+	if err != nil {
+		return nil, err
+	}
+
+	budget := p.MaxContextTokens
+	if budget <= 0 {
+		budget = 3000
+	}
+	counter := p.Counter
+	if counter == nil {
+		counter = deepseek.NewTokenizer(deepseek.DeepSeekChat)
+	}
+
+	var sources []Source
+	for _, r := range results {
+		source := sourceFromResult(r)
+		// A chunk that does not fit is skipped rather than ending the loop, so
+		// a smaller, lower ranked chunk can still use the rest of the budget.
+		n := counter.CountTokens(source.ID) + counter.CountTokens(source.Text) + 2
+		if n > budget {
+			continue
+		}
+		budget -= n
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+func sourceFromResult(r vectorstore.Result) Source {
+	source := Source{ID: r.ID, Score: r.Score, Metadata: r.Metadata}
+	source.Text, _ = r.Metadata[MetadataText].(string)
+	source.DocumentID, _ = r.Metadata[MetadataDocument].(string)
+	source.Start = intValue(r.Metadata[MetadataStart])
+	source.End = intValue(r.Metadata[MetadataEnd])
+	return source
+}
+
+// intValue reads an offset that may have become a float64 in a snapshot.
+func intValue(v any) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	}
+	return 0
+}
+
+// Messages retrieves sources for question and returns the chat messages
+// asking it, for callers that drive the chat themselves.
+func (p *Pipeline) Messages(ctx context.Context, question string) ([]deepseek.ChatCompletionMessage, []Source, error) {
+	sources, err := p.Retrieve(ctx, question)
+	if err != nil {
+		return nil, nil, err
+	}
+	prompt := p.Prompt
+	if prompt == nil {
+		prompt = DefaultPrompt
+	}
+	var system strings.Builder
+	if err := prompt.Execute(&system, struct {
+		Question string
+		Sources  []Source
+	}{question, sources}); err != nil {
+		return nil, nil, err
+	}
+	return []deepseek.ChatCompletionMessage{
+		{Role: deepseek.ChatMessageRoleSystem, Content: system.String()},
+		{Role: deepseek.ChatMessageRoleUser, Content: question},
+	}, sources, nil
+}
+
+// Ask answers question from the stored documents.
+func (p *Pipeline) Ask(ctx context.Context, question string) (*Answer, error) {
+	messages, sources, err := p.Messages(ctx, question)
+	if err != nil {
+		return nil, err
+	}
+	text, err := p.Chat.Complete(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return &Answer{Text: text, Citations: Citations(text, sources), Sources: sources}, nil
+}
+
+// AskStream is like Ask but calls onDelta with each piece of the answer as it
+// arrives. Citations are resolved once the answer is complete.
+func (p *Pipeline) AskStream(ctx context.Context, question string, onDelta func(string) error) (*Answer, error) {
+	messages, sources, err := p.Messages(ctx, question)
+	if err != nil {
+		return nil, err
+	}
+	text, err := p.Chat.Stream(ctx, messages, onDelta)
+	if err != nil {
+		return nil, err
+	}
+	return &Answer{Text: text, Citations: Citations(text, sources), Sources: sources}, nil
+}
+
+var citationPattern = regexp.MustCompile(`\[([^\[\]]+)\]`)
+
+// Citations returns the IDs of sources cited in text as [id] or [id1, id2],
+// in order of first citation. Bracketed text naming no source is ignored.
+func Citations(text string, sources []Source) []string {
+	known := make(map[string]bool, len(sources))
+	for _, s := range sources {
+		known[s.ID] = true
+	}
+	var cited []string
+	seen := make(map[string]bool)
+	for _, m := range citationPattern.FindAllStringSubmatch(text, -1) {
+		for _, id := range strings.FieldsFunc(m[1], func(r rune) bool { return r == ',' || r == ';' }) {
+			id = strings.TrimSpace(id)
+			if known[id] && !seen[id] {
+				seen[id] = true
+				cited = append(cited, id)
+			}
+		}
+	}
+	return cited
+}
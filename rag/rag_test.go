@@ -0,0 +1,179 @@
+package rag
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	deepseek "github.com/p9966/go-deepseek"
+	"github.com/p9966/go-deepseek/textsplitter"
+	"github.com/p9966/go-deepseek/vectorstore"
+)
+
+// wordEmbedder embeds texts as counts of a fixed vocabulary.
+type wordEmbedder struct{}
+
+var vocabulary = []string{"cat", "dog", "fish", "bird"}
+
+func (wordEmbedder) Embed(ctx context.Context, inputs []string) ([][]float64, error) {
+	out := make([][]float64, len(inputs))
+	for i, input := range inputs {
+		v := make([]float64, len(vocabulary))
+		for j, word := range vocabulary {
+			v[j] = float64(strings.Count(strings.ToLower(input), word)) + 0.01
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+type fakeChat struct {
+	reply    string
+	messages []deepseek.ChatCompletionMessage
+}
+
+func (c *fakeChat) Complete(ctx context.Context, messages []deepseek.ChatCompletionMessage) (string, error) {
+	c.messages = messages
+	return c.reply, nil
+}
+
+func (c *fakeChat) Stream(ctx context.Context, messages []deepseek.ChatCompletionMessage, onDelta func(string) error) (string, error) {
+	c.messages = messages
+	for _, word := range strings.SplitAfter(c.reply, " ") {
+		if err := onDelta(word); err != nil {
+			return "", err
+		}
+	}
+	return c.reply, nil
+}
+
+func newTestPipeline(t *testing.T, chat Chat) *Pipeline {
+	t.Helper()
+	p := &Pipeline{
+		Embedder: wordEmbedder{},
+		Store:    vectorstore.NewFlat(vectorstore.Cosine),
+		Chat:     chat,
+		Splitter: textsplitter.Recursive{Size: 40},
+		TopK:     2,
+	}
+	ids, err := p.AddDocuments(context.Background(),
+		Document{ID: "pets", Text: "A cat sleeps all day long.\n\nA dog wants a walk twice a day."},
+		Document{ID: "sea", Text: "A fish swims in the sea.", Metadata: map[string]any{"topic": "water"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(ids, ",") != "pets#0,pets#1,sea#0" {
+		t.Fatalf("ids = %v", ids)
+	}
+	return p
+}
+
+func TestAskCitesRetrievedChunks(t *testing.T) {
+	chat := &fakeChat{reply: "Dogs need walks [pets#1]. Cats sleep [pets#0, made-up]."}
+	p := newTestPipeline(t, chat)
+
+	answer, err := p.Ask(context.Background(), "How often does the dog walk?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer.Sources[0].ID != "pets#1" || answer.Sources[0].Text != "A dog wants a walk twice a day." {
+		t.Fatalf("best source = %+v", answer.Sources[0])
+	}
+	doc := "A cat sleeps all day long.\n\nA dog wants a walk twice a day."
+	if s := answer.Sources[0]; doc[s.Start:s.End] != s.Text || s.DocumentID != "pets" {
+		t.Fatalf("source offsets = %+v", s)
+	}
+	if got := strings.Join(answer.Citations, ","); got != "pets#1,pets#0" {
+		t.Fatalf("citations = %s", got)
+	}
+	if system := chat.messages[0].Content; !strings.Contains(system, "[pets#1]\nA dog wants a walk") {
+		t.Fatalf("system prompt = %q", system)
+	}
+	if chat.messages[1].Content != "How often does the dog walk?" {
+		t.Fatalf("user message = %q", chat.messages[1].Content)
+	}
+}
+
+func TestRetrieveRespectsTokenBudgetAndFilter(t *testing.T) {
+	p := newTestPipeline(t, &fakeChat{})
+	p.Counter = deepseek.TokenCounterFunc(func(s string) int { return len(s) })
+	p.MaxContextTokens = 40
+
+	sources, err := p.Retrieve(context.Background(), "dog and cat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != 1 {
+		t.Fatalf("sources = %+v, want one within budget", sources)
+	}
+
+	p.MaxContextTokens = 0
+	p.Filter = vectorstore.Eq("topic", "water")
+	sources, _ = p.Retrieve(context.Background(), "dog")
+	if len(sources) != 1 || sources[0].ID != "sea#0" {
+		t.Fatalf("filtered sources = %+v", sources)
+	}
+}
+
+func TestAskStreamWithClientChat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Fish swim \"}}]}\n\n"))
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"[sea#0].\"}}]}\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	p := newTestPipeline(t, &ClientChat{Client: &deepseek.Client{BaseUrl: server.URL}, Model: deepseek.DeepSeekChat})
+	var deltas []string
+	answer, err := p.AskStream(context.Background(), "Where do fish live?", func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deltas) != 2 || answer.Text != "Fish swim [sea#0]." {
+		t.Fatalf("deltas = %q, answer = %q", deltas, answer.Text)
+	}
+	if len(answer.Citations) != 1 || answer.Citations[0] != "sea#0" {
+		t.Fatalf("citations = %v", answer.Citations)
+	}
+}
+
+func TestAddDocumentsReplacesChunks(t *testing.T) {
+	p := newTestPipeline(t, &fakeChat{})
+	ids, err := p.AddDocuments(context.Background(), Document{ID: "pets", Text: "A fish swims."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || p.Store.Len() != 2 {
+		t.Fatalf("ids = %v, stored = %d", ids, p.Store.Len())
+	}
+	if _, ok := p.Store.Get("pets#1"); ok {
+		t.Fatal("chunk of the earlier version was kept")
+	}
+}
+
+func TestAskWithModelChat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"model":"qwen2.5:7b","message":{"role":"assistant","content":"Fish swim [sea#0]."},"done":true}`))
+	}))
+	defer server.Close()
+
+	model, err := deepseek.NewChatModel(deepseek.ProviderConfig{Provider: deepseek.ProviderOllama, Model: "qwen2.5:7b", BaseUrl: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newTestPipeline(t, &ModelChat{Model: model})
+	answer, err := p.Ask(context.Background(), "Where do fish live?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer.Text != "Fish swim [sea#0]." || len(answer.Citations) != 1 {
+		t.Fatalf("answer = %+v", answer)
+	}
+}
@@ -0,0 +1,471 @@
+package deepseek
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheBackend stores cached responses by key. Implementations must be safe
+// for concurrent use.
+type CacheBackend interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte) error
+	Delete(key string)
+}
+
+// LRUCache is an in-memory CacheBackend that evicts the least recently used
+// entry beyond its capacity.
+type LRUCache struct {
+	capacity int
+
+	mu      sync.Mutex
+	order   *list.List // front is most recently used
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value []byte
+}
+
+// NewLRUCache creates an LRUCache holding up to capacity entries.
+func NewLRUCache(capacity int) *LRUCache {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &LRUCache{capacity: capacity, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (c *LRUCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*lruEntry).value, true
+}
+
+func (c *LRUCache) Set(key string, value []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*lruEntry).value = value
+		c.order.MoveToFront(e)
+		return nil
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+func (c *LRUCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.order.Remove(e)
+		delete(c.entries, key)
+	}
+}
+
+// Len returns the number of entries.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// DiskCache is a CacheBackend keeping one file per entry under a directory,
+// so cached responses survive restarts and can be shared between runs.
+type DiskCache struct {
+	dir string
+}
+
+// NewDiskCache creates a DiskCache in dir, creating the directory if needed.
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+func (c *DiskCache) path(key string) string {
+	if len(key) < 2 {
+		return filepath.Join(c.dir, key)
+	}
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	value, err := os.ReadFile(c.path(key))
+	return value, err == nil
+}
+
+// Set writes the entry through a temporary file, so concurrent readers never
+// see a partial entry.
+func (c *DiskCache) Set(key string, value []byte) error {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), key+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(value); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func (c *DiskCache) Delete(key string) {
+	os.Remove(c.path(key))
+}
+
+// CacheMode controls how a request uses a ResponseCache.
+type CacheMode int
+
+const (
+	CacheDefault CacheMode = iota // serve from the cache and store misses
+	CacheRefresh                  // skip the lookup but store the fresh response
+	CacheBypass                   // neither read nor write the cache
+)
+
+type cacheModeKey struct{}
+
+// WithCacheMode sets the cache mode of requests made with ctx.
+func WithCacheMode(ctx context.Context, mode CacheMode) context.Context {
+	return context.WithValue(ctx, cacheModeKey{}, mode)
+}
+
+func cacheModeFrom(ctx context.Context) CacheMode {
+	mode, _ := ctx.Value(cacheModeKey{}).(CacheMode)
+	return mode
+}
+
+// ResponseCache replays responses to identical requests. The
+// key is a hash of the method, host, path and canonical JSON body, so field
+// order and whitespace do not matter. Requests carrying an Authorization
+// header are further keyed by a hash of it, so a cache shared between API
+// keys or tenants, such as a DiskCache reused across runs, never replays one
+// key's responses to another. A KeyPool installed after the cache rotates
+// keys below it, and its requests share entries. Streaming responses are stored once the
+// stream has been read to the end and replayed as a stream, so
+// CreateChatCompletionStream returns a regular ChatCompletionStream.
+//
+// Replayed responses have Cached set and zero usage, since nothing was
+// billed. Responses to sampling requests are replayed as well; the cache is
+// meant for deterministic workloads such as evaluation runs.
+type ResponseCache struct {
+	Backend CacheBackend
+	TTL     time.Duration // Optional: lifetime of an entry, forever if zero
+	Paths   []string      // Optional: endpoint suffixes to cache, defaults to chat, FIM and embeddings
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type cachedEntry struct {
+	StoredAt    time.Time `json:"stored_at"`
+	ContentType string    `json:"content_type,omitempty"`
+	Body        []byte    `json:"body"`
+}
+
+// ResponseCacheStats counts cache lookups.
+type ResponseCacheStats struct {
+	Hits   int64
+	Misses int64
+}
+
+// Stats returns the lookups so far.
+func (c *ResponseCache) Stats() ResponseCacheStats {
+	return ResponseCacheStats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
+func (c *ResponseCache) cacheable(req *http.Request) bool {
+	if req.Method != http.MethodPost || req.Body == nil {
+		return false
+	}
+	paths := c.Paths
+	if paths == nil {
+		paths = []string{chatCompletionSuffix, finCompletionSuffix, embeddingsSuffix}
+	}
+	for _, p := range paths {
+		if strings.HasSuffix(req.URL.Path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware returns the middleware serving requests from the cache.
+func (c *ResponseCache) Middleware() Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			mode := cacheModeFrom(req.Context())
+			if mode == CacheBypass || !c.cacheable(req) {
+				return next.Do(req)
+			}
+			body, err := io.ReadAll(req.Body)
+			req.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			key := CacheKey(req.Method, req.URL.Host+req.URL.Path, body)
+			if auth := req.Header.Get("Authorization"); auth != "" {
+				key = credentialKey(key, auth)
+			}
+
+			if mode == CacheDefault {
+				if entry, ok := c.get(key); ok {
+					c.hits.Add(1)
+					return replayResponse(req, entry), nil
+				}
+			}
+			c.misses.Add(1)
+
+			resp, err := next.Do(req)
+			if err != nil || resp.StatusCode != http.StatusOK {
+				return resp, err
+			}
+			contentType := resp.Header.Get("Content-Type")
+			store := func(data []byte) {
+				entry, _ := json.Marshal(cachedEntry{StoredAt: time.Now(), ContentType: contentType, Body: data})
+				_ = c.Backend.Set(key, entry)
+			}
+			if isStreamContentType(contentType) {
+				resp.Body = &recordingBody{ReadCloser: resp.Body, onComplete: store}
+				return resp, nil
+			}
+			data, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(data))
+			store(data)
+			return resp, nil
+		})
+	}
+}
+
+func (c *ResponseCache) get(key string) (cachedEntry, bool) {
+	data, ok := c.Backend.Get(key)
+	if !ok {
+		return cachedEntry{}, false
+	}
+	var entry cachedEntry
+	if json.Unmarshal(data, &entry) != nil {
+		c.Backend.Delete(key)
+		return cachedEntry{}, false
+	}
+	if c.TTL > 0 && time.Since(entry.StoredAt) > c.TTL {
+		c.Backend.Delete(key)
+		return cachedEntry{}, false
+	}
+	return entry, true
+}
+
+// CacheKey returns the ResponseCache key of a request to endpoint, the host
+// and path of the URL. JSON bodies are canonicalized first.
+func CacheKey(method, endpoint string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + endpoint + "\n"))
+	h.Write(canonicalJSON(body))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// credentialKey scopes key to the credential auth. Only a hash of auth is
+// mixed in, so the credential cannot be read back from stored keys.
+func credentialKey(key, auth string) string {
+	credential := sha256.Sum256([]byte(auth))
+	h := sha256.New()
+	h.Write([]byte(key + "\n"))
+	h.Write(credential[:])
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// canonicalJSON re-encodes JSON with sorted keys and no insignificant
+// whitespace. Other bodies are returned unchanged.
+func canonicalJSON(body []byte) []byte {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if dec.Decode(&v) != nil {
+		return body
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// recordingBody passes a streamed body through and hands the complete body to
+// onComplete once its final event has been read. Streams abandoned or cut off
+// early are not stored.
+type recordingBody struct {
+	io.ReadCloser
+	buf        bytes.Buffer
+	onComplete func([]byte)
+	done       bool
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if errors.Is(err, io.EOF) && streamFinished(b.buf.Bytes()) {
+		b.complete()
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	if streamFinished(b.buf.Bytes()) {
+		b.complete()
+	}
+	return b.ReadCloser.Close()
+}
+
+func (b *recordingBody) complete() {
+	if !b.done {
+		b.done = true
+		b.onComplete(b.buf.Bytes())
+	}
+}
+
+// streamFinished reports whether a stream ends with its final event: the
+// [DONE] marker of server-sent events or the done object of Ollama.
+func streamFinished(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	if bytes.HasSuffix(data, []byte("data: [DONE]")) {
+		return true
+	}
+	last := data[bytes.LastIndexByte(data, '\n')+1:]
+	var event struct {
+		Done bool `json:"done"`
+	}
+	return json.Unmarshal(last, &event) == nil && event.Done
+}
+
+func isStreamContentType(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/event-stream") || strings.HasPrefix(contentType, "application/x-ndjson")
+}
+
+// replayResponse builds the response of a cache hit.
+func replayResponse(req *http.Request, entry cachedEntry) *http.Response {
+	body := entry.Body
+	contentType := entry.ContentType
+	switch {
+	case strings.HasPrefix(contentType, "text/event-stream"):
+		body = markStreamCached(body)
+	case isStreamContentType(contentType):
+	default:
+		body = markCached(body)
+		if contentType == "" {
+			contentType = "application/json"
+		}
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {contentType}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// markCached sets "cached" on a JSON object and zeroes the numbers in its
+// usage, so replayed calls are not billed twice in a UsageLedger. Bodies that
+// are not JSON objects are returned unchanged.
+func markCached(body []byte) []byte {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil || fields == nil {
+		return body
+	}
+	fields["cached"] = json.RawMessage("true")
+	if usage, ok := fields["usage"]; ok {
+		fields["usage"] = zeroNumbers(usage)
+	}
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// markStreamCached applies markCached to the usage carrying events of a
+// server-sent event stream.
+func markStreamCached(body []byte) []byte {
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), len(body)+1)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if data, ok := bytes.CutPrefix(line, []byte("data: ")); ok && bytes.Contains(data, []byte(`"usage"`)) {
+			out.WriteString("data: ")
+			out.Write(markCached(data))
+		} else {
+			out.Write(line)
+		}
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
+
+func zeroNumbers(raw json.RawMessage) json.RawMessage {
+	var v any
+	if json.Unmarshal(raw, &v) != nil {
+		return raw
+	}
+	var zero func(v any) any
+	zero = func(v any) any {
+		switch t := v.(type) {
+		case float64:
+			return 0
+		case map[string]any:
+			for k, x := range t {
+				t[k] = zero(x)
+			}
+		case []any:
+			for i, x := range t {
+				t[i] = zero(x)
+			}
+		}
+		return v
+	}
+	out, err := json.Marshal(zero(v))
+	if err != nil {
+		return raw
+	}
+	return out
+}
@@ -0,0 +1,161 @@
+package deepseek
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheKeyIsCanonical(t *testing.T) {
+	a := CacheKey(http.MethodPost, "api.deepseek.com/chat/completions", []byte(`{"model":"deepseek-chat","temperature":0}`))
+	b := CacheKey(http.MethodPost, "api.deepseek.com/chat/completions", []byte("{\n  \"temperature\": 0,\n  \"model\": \"deepseek-chat\"\n}"))
+	c := CacheKey(http.MethodPost, "api.deepseek.com/beta/completions", []byte(`{"model":"deepseek-chat","temperature":0}`))
+	if a != b || a == c {
+		t.Fatalf("keys a=%s b=%s c=%s", a, b, c)
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"1","choices":[{"message":{"role":"assistant","content":"4"}}],
+			"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6,"prompt_tokens_details":{"cached_tokens":2}}}`))
+	}))
+	defer server.Close()
+
+	ledger := &UsageLedger{}
+	cache := &ResponseCache{Backend: NewLRUCache(10)}
+	client := &Client{BaseUrl: server.URL, Ledger: ledger}
+	client.Use(cache.Middleware())
+	req := &ChatCompletionRequest{Model: DeepSeekChat, Messages: []ChatCompletionMessage{{Role: ChatMessageRoleUser, Content: "2+2?"}}}
+
+	first, err := client.CreateChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := client.CreateChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Cached || !second.Cached || second.Choices[0].Message.Content != "4" {
+		t.Fatalf("first cached=%v, second cached=%v", first.Cached, second.Cached)
+	}
+	if second.Usage.TotalTokens != 0 || second.Usage.PromptTokensDetails.CachedTokens != 0 {
+		t.Fatalf("replayed usage = %+v", second.Usage)
+	}
+	if total := ledger.Total(); total.Usage.TotalTokens != 6 {
+		t.Fatalf("ledger tokens = %d, want 6", total.Usage.TotalTokens)
+	}
+
+	if _, err := client.CreateChatCompletion(WithCacheMode(context.Background(), CacheBypass), req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CreateChatCompletion(WithCacheMode(context.Background(), CacheRefresh), req); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("calls = %d, want 3", calls.Load())
+	}
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 2 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestResponseCacheReplaysStreams(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n"))
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"lo\"},\"finish_reason\":\"stop\"}],\"usage\":{\"total_tokens\":7}}\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	backend, err := NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := &ResponseCache{Backend: backend, TTL: time.Hour}
+	client := &Client{BaseUrl: server.URL}
+	client.Use(cache.Middleware())
+
+	read := func() (string, *Usage) {
+		t.Helper()
+		stream, err := client.CreateChatCompletionStream(context.Background(), StreamChatCompletionRequest{Model: DeepSeekChat, Messages: []ChatCompletionMessage{{Role: ChatMessageRoleUser, Content: "hi"}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stream.Close()
+		var acc StreamAccumulator
+		for {
+			resp, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return acc.Content(), acc.Usage()
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			acc.Add(resp)
+		}
+	}
+
+	if content, usage := read(); content != "Hello" || usage.TotalTokens != 7 {
+		t.Fatalf("first stream = %q, usage %+v", content, usage)
+	}
+	if content, usage := read(); content != "Hello" || usage.TotalTokens != 0 {
+		t.Fatalf("replayed stream = %q, usage %+v", content, usage)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestLRUCacheEvicts(t *testing.T) {
+	c := NewLRUCache(2)
+	c.Set("a", []byte("1"))
+	c.Set("b", []byte("2"))
+	c.Get("a")
+	c.Set("c", []byte("3"))
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("least recently used entry was kept")
+	}
+	if _, ok := c.Get("a"); !ok || c.Len() != 2 {
+		t.Fatal("recently used entry was evicted")
+	}
+}
+
+func TestResponseCacheIsScopedToCredential(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"` + r.Header.Get("Authorization") + `"}}]}`))
+	}))
+	defer server.Close()
+
+	backend := NewLRUCache(10)
+	req := &ChatCompletionRequest{Model: DeepSeekChat, Messages: []ChatCompletionMessage{{Role: ChatMessageRoleUser, Content: "Who am I?"}}}
+	for _, token := range []string{"tenant-a", "tenant-b", "tenant-a"} {
+		cache := &ResponseCache{Backend: backend}
+		client := &Client{BaseUrl: server.URL, AuthToken: token}
+		client.Use(cache.Middleware())
+		resp, err := client.CreateChatCompletion(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Choices[0].Message.Content != token {
+			t.Fatalf("%s was answered with the response of %s", token, resp.Choices[0].Message.Content)
+		}
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("calls = %d, want one per credential", calls.Load())
+	}
+}
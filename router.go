@@ -0,0 +1,354 @@
+package deepseek
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoBackendAvailable is returned by a Router when every backend's circuit
+// is open.
+var ErrNoBackendAvailable = errors.New("no backend available")
+
+// ErrorClass groups the errors a Router reacts to.
+type ErrorClass int
+
+const (
+	ErrorClassOther         ErrorClass = iota
+	ErrorClassRateLimit                // HTTP 429
+	ErrorClassServer                   // HTTP 5xx
+	ErrorClassTimeout                  // a deadline or network timeout
+	ErrorClassContextLength            // the prompt does not fit the model's context window
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorClassRateLimit:
+		return "rate_limit"
+	case ErrorClassServer:
+		return "server"
+	case ErrorClassTimeout:
+		return "timeout"
+	case ErrorClassContextLength:
+		return "context_length"
+	}
+	return "other"
+}
+
+// contextLengthMessages are fragments of the errors providers return for
+// prompts longer than the context window.
+var contextLengthMessages = []string{
+	"context length",
+	"context_length",
+	"context window",
+	"input length",
+	"too many tokens",
+	"prompt is too long",
+}
+
+// ClassifyError returns the class of an error returned by a ChatModel or a
+// Client method.
+func ClassifyError(err error) ErrorClass {
+	if errors.Is(err, ErrContextTooLong) {
+		return ErrorClassContextLength
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusTooManyRequests:
+			return ErrorClassRateLimit
+		case apiErr.StatusCode >= 500:
+			return ErrorClassServer
+		case apiErr.StatusCode == http.StatusBadRequest || apiErr.StatusCode == http.StatusRequestEntityTooLarge:
+			body := strings.ToLower(apiErr.Body)
+			for _, msg := range contextLengthMessages {
+				if strings.Contains(body, msg) {
+					return ErrorClassContextLength
+				}
+			}
+		}
+		return ErrorClassOther
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return ErrorClassTimeout
+	}
+	return ErrorClassOther
+}
+
+// FallbackAction is what a Router does after a backend fails.
+type FallbackAction int
+
+const (
+	FallbackNext FallbackAction = iota // try the next backend
+	FallbackStop                       // return the error to the caller
+)
+
+// CircuitState is the state of a backend's circuit breaker.
+type CircuitState int
+
+const (
+	CircuitClosed   CircuitState = iota // requests are sent
+	CircuitOpen                         // requests skip the backend until the cooldown ends
+	CircuitHalfOpen                     // one trial request decides whether to close the circuit
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// Backend is a named ChatModel served by a Router.
+type Backend struct {
+	Name  string
+	Model ChatModel
+}
+
+// BackendHealth reports the health of a Router backend.
+type BackendHealth struct {
+	Name                string
+	State               CircuitState
+	Successes           int64
+	Failures            int64
+	ConsecutiveFailures int
+	LastError           error
+	LastLatency         time.Duration // Latency of the last successful request, until the first chunk for streams
+}
+
+// Router is a ChatModel that sends each request to the first healthy backend
+// in priority order and falls back to the next one on failure. The backend
+// that served a reply is reported in GenerateResponse.Backend and
+// GenerateChunk.Backend.
+//
+// By default rate limits, server errors, timeouts and context length errors
+// fall back, and other errors are returned. Rate limits, server errors and
+// timeouts count as failures of the backend; FailureThreshold consecutive
+// failures open its circuit, which skips it for Cooldown and then lets one
+// trial request through. Streams fall back only until they are opened.
+type Router struct {
+	Backends         []Backend                       // in priority order
+	Rules            map[ErrorClass]FallbackAction   // Optional: overrides the default action of an error class
+	AttemptTimeout   time.Duration                   // Optional: bounds each attempt, until the stream is opened for streams
+	FailureThreshold int                             // Optional: consecutive failures opening a circuit, defaults to 5
+	Cooldown         time.Duration                   // Optional: how long an open circuit skips its backend, defaults to 30s
+	OnFallback       func(backend string, err error) // Optional: called when a failed backend is followed by the next one
+
+	mu     sync.Mutex
+	health []backendHealth
+}
+
+type backendHealth struct {
+	BackendHealth
+	openedAt time.Time
+	trial    bool // a half-open trial request is in flight
+}
+
+func (r *Router) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	var errs []error
+	for i, b := range r.Backends {
+		if !r.acquire(i) {
+			continue
+		}
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if r.AttemptTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, r.AttemptTimeout)
+		}
+		start := time.Now()
+		resp, err := b.Model.Generate(attemptCtx, req)
+		cancel()
+		if err == nil {
+			r.succeeded(i, time.Since(start))
+			resp.Backend = b.Name
+			return resp, nil
+		}
+		err = fmt.Errorf("%s: %w", b.Name, err)
+		if !r.failed(ctx, i, err, i < len(r.Backends)-1) {
+			return nil, errors.Join(append(errs, err)...)
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, ErrNoBackendAvailable
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (r *Router) Stream(ctx context.Context, req *GenerateRequest) (GenerateStream, error) {
+	var errs []error
+	for i, b := range r.Backends {
+		if !r.acquire(i) {
+			continue
+		}
+		attemptCtx, cancel := context.WithCancel(ctx)
+		var timer *time.Timer
+		if r.AttemptTimeout > 0 {
+			timer = time.AfterFunc(r.AttemptTimeout, cancel)
+		}
+		start := time.Now()
+		stream, err := b.Model.Stream(attemptCtx, req)
+		if timer != nil && !timer.Stop() {
+			if err == nil {
+				stream.Close()
+			}
+			err = fmt.Errorf("timed out after %s: %w", r.AttemptTimeout, context.DeadlineExceeded)
+		}
+		if err == nil {
+			r.succeeded(i, time.Since(start))
+			return &routerStream{GenerateStream: stream, backend: b.Name, cancel: cancel}, nil
+		}
+		cancel()
+		err = fmt.Errorf("%s: %w", b.Name, err)
+		if !r.failed(ctx, i, err, i < len(r.Backends)-1) {
+			return nil, errors.Join(append(errs, err)...)
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, ErrNoBackendAvailable
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Health returns the health of every backend, in priority order.
+func (r *Router) Health() []BackendHealth {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.init()
+	out := make([]BackendHealth, len(r.health))
+	for i, h := range r.health {
+		out[i] = h.BackendHealth
+		if h.State == CircuitOpen && time.Since(h.openedAt) >= r.cooldown() {
+			out[i].State = CircuitHalfOpen
+		}
+	}
+	return out
+}
+
+func (r *Router) init() {
+	if len(r.health) != len(r.Backends) {
+		r.health = make([]backendHealth, len(r.Backends))
+		for i, b := range r.Backends {
+			r.health[i].Name = b.Name
+		}
+	}
+}
+
+func (r *Router) cooldown() time.Duration {
+	if r.Cooldown > 0 {
+		return r.Cooldown
+	}
+	return 30 * time.Second
+}
+
+func (r *Router) rule(class ErrorClass) FallbackAction {
+	if action, ok := r.Rules[class]; ok {
+		return action
+	}
+	if class == ErrorClassOther {
+		return FallbackStop
+	}
+	return FallbackNext
+}
+
+// acquire reports whether backend i may take a request, starting a trial
+// request if its circuit has cooled down.
+func (r *Router) acquire(i int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.init()
+	h := &r.health[i]
+	switch h.State {
+	case CircuitOpen:
+		if time.Since(h.openedAt) < r.cooldown() {
+			return false
+		}
+		h.State = CircuitHalfOpen
+	case CircuitHalfOpen:
+		if h.trial {
+			return false
+		}
+	default:
+		return true
+	}
+	h.trial = true
+	return true
+}
+
+func (r *Router) succeeded(i int, latency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h := &r.health[i]
+	h.Successes++
+	h.ConsecutiveFailures = 0
+	h.LastLatency = latency
+	h.State = CircuitClosed
+	h.trial = false
+}
+
+// failed records the failure of backend i and reports whether the next
+// backend should be tried.
+func (r *Router) failed(ctx context.Context, i int, err error, hasNext bool) bool {
+	class := ClassifyError(err)
+	r.mu.Lock()
+	h := &r.health[i]
+	h.trial = false
+	// A request canceled by the caller says nothing about the backend.
+	if ctx.Err() != nil {
+		r.mu.Unlock()
+		return false
+	}
+	h.LastError = err
+	switch class {
+	case ErrorClassRateLimit, ErrorClassServer, ErrorClassTimeout:
+		h.Failures++
+		h.ConsecutiveFailures++
+		threshold := r.FailureThreshold
+		if threshold <= 0 {
+			threshold = 5
+		}
+		if h.State == CircuitHalfOpen || h.ConsecutiveFailures >= threshold {
+			h.State = CircuitOpen
+			h.openedAt = time.Now()
+		}
+	}
+	// Other errors say nothing about recovery, so a half-open circuit stays
+	// half-open and the next request is another trial.
+	r.mu.Unlock()
+
+	if r.rule(class) != FallbackNext {
+		return false
+	}
+	if hasNext && r.OnFallback != nil {
+		r.OnFallback(r.Backends[i].Name, err)
+	}
+	return true
+}
+
+type routerStream struct {
+	GenerateStream
+	backend string
+	cancel  context.CancelFunc
+}
+
+func (s *routerStream) Recv() (*GenerateChunk, error) {
+	chunk, err := s.GenerateStream.Recv()
+	if chunk != nil {
+		chunk.Backend = s.backend
+	}
+	return chunk, err
+}
+
+func (s *routerStream) Close() error {
+	defer s.cancel()
+	return s.GenerateStream.Close()
+}
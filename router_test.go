@@ -0,0 +1,185 @@
+package deepseek
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// scriptedModel fails with the next scripted error, then replies.
+type scriptedModel struct {
+	errs  []error
+	calls int
+}
+
+func (m *scriptedModel) next() error {
+	m.calls++
+	if len(m.errs) == 0 {
+		return nil
+	}
+	err := m.errs[0]
+	m.errs = m.errs[1:]
+	return err
+}
+
+func (m *scriptedModel) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	if err := m.next(); err != nil {
+		return nil, err
+	}
+	return &GenerateResponse{Message: ChatCompletionMessage{Role: ChatMessageRoleAssistant, Content: "ok"}}, nil
+}
+
+func (m *scriptedModel) Stream(ctx context.Context, req *GenerateRequest) (GenerateStream, error) {
+	if err := m.next(); err != nil {
+		return nil, err
+	}
+	return &sliceStream{chunks: []*GenerateChunk{{Content: "ok"}}}, nil
+}
+
+type sliceStream struct {
+	chunks []*GenerateChunk
+}
+
+func (s *sliceStream) Recv() (*GenerateChunk, error) {
+	if len(s.chunks) == 0 {
+		return nil, io.EOF
+	}
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return chunk, nil
+}
+
+func (s *sliceStream) Close() error { return nil }
+
+func TestClassifyError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"message":"This model's maximum context length is 65536 tokens."}}`))
+	}))
+	defer server.Close()
+	_, err := (&Client{BaseUrl: server.URL}).CreateChatCompletion(context.Background(), &ChatCompletionRequest{Model: DeepSeekChat})
+
+	tests := []struct {
+		err  error
+		want ErrorClass
+	}{
+		{err, ErrorClassContextLength},
+		{&APIError{StatusCode: http.StatusTooManyRequests}, ErrorClassRateLimit},
+		{&APIError{StatusCode: http.StatusBadGateway}, ErrorClassServer},
+		{&APIError{StatusCode: http.StatusUnauthorized}, ErrorClassOther},
+		{context.DeadlineExceeded, ErrorClassTimeout},
+		{ErrContextTooLong, ErrorClassContextLength},
+	}
+	for _, tt := range tests {
+		if got := ClassifyError(tt.err); got != tt.want {
+			t.Errorf("ClassifyError(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRouterFallsBack(t *testing.T) {
+	primary := &scriptedModel{errs: []error{&APIError{StatusCode: http.StatusTooManyRequests}, &APIError{StatusCode: http.StatusUnauthorized}}}
+	secondary := &scriptedModel{}
+	var fellBack []string
+	router := &Router{
+		Backends:   []Backend{{Name: "deepseek", Model: primary}, {Name: "ollama", Model: secondary}},
+		OnFallback: func(backend string, err error) { fellBack = append(fellBack, backend) },
+	}
+
+	resp, err := router.Generate(context.Background(), &GenerateRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Backend != "ollama" || len(fellBack) != 1 {
+		t.Fatalf("backend = %s, fallbacks = %v", resp.Backend, fellBack)
+	}
+
+	// Errors of other classes are returned without falling back.
+	_, err = router.Generate(context.Background(), &GenerateRequest{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || secondary.calls != 1 {
+		t.Fatalf("err = %v, secondary calls = %d", err, secondary.calls)
+	}
+
+	stream, err := router.Stream(context.Background(), &GenerateRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	if chunk, err := stream.Recv(); err != nil || chunk.Backend != "deepseek" {
+		t.Fatalf("chunk = %+v, err = %v", chunk, err)
+	}
+}
+
+func TestRouterCircuitBreaker(t *testing.T) {
+	unavailable := &APIError{StatusCode: http.StatusServiceUnavailable}
+	primary := &scriptedModel{errs: []error{unavailable, unavailable, unavailable}}
+	router := &Router{
+		Backends:         []Backend{{Name: "primary", Model: primary}, {Name: "backup", Model: &scriptedModel{}}},
+		FailureThreshold: 2,
+		Cooldown:         20 * time.Millisecond,
+	}
+	generate := func() string {
+		t.Helper()
+		resp, err := router.Generate(context.Background(), &GenerateRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Backend
+	}
+
+	generate()
+	generate()
+	if state := router.Health()[0].State; state != CircuitOpen {
+		t.Fatalf("state = %s, want open", state)
+	}
+	generate()
+	if primary.calls != 2 {
+		t.Fatalf("open circuit was called: %d calls", primary.calls)
+	}
+
+	// The trial request after the cooldown fails and reopens the circuit,
+	// the next trial succeeds and closes it.
+	time.Sleep(30 * time.Millisecond)
+	generate()
+	time.Sleep(30 * time.Millisecond)
+	if backend := generate(); backend != "primary" {
+		t.Fatalf("backend = %s, want primary", backend)
+	}
+	if h := router.Health()[0]; h.State != CircuitClosed || h.Failures != 3 || h.Successes != 1 {
+		t.Fatalf("health = %+v", h)
+	}
+}
+
+func TestRouterKeepsFallbackErrors(t *testing.T) {
+	router := &Router{Backends: []Backend{
+		{Name: "primary", Model: &scriptedModel{errs: []error{&APIError{StatusCode: http.StatusTooManyRequests}}}},
+		{Name: "backup", Model: &scriptedModel{errs: []error{&APIError{StatusCode: http.StatusUnauthorized}}}},
+	}}
+	_, err := router.Generate(context.Background(), &GenerateRequest{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("err = %v, want the primary's rate limit too", err)
+	}
+}
+
+func TestRouterTrialOtherErrorStaysHalfOpen(t *testing.T) {
+	unavailable := &APIError{StatusCode: http.StatusServiceUnavailable}
+	primary := &scriptedModel{errs: []error{unavailable, &APIError{StatusCode: http.StatusUnauthorized}}}
+	router := &Router{
+		Backends:         []Backend{{Name: "primary", Model: primary}, {Name: "backup", Model: &scriptedModel{}}},
+		Rules:            map[ErrorClass]FallbackAction{ErrorClassOther: FallbackNext},
+		FailureThreshold: 1,
+		Cooldown:         10 * time.Millisecond,
+	}
+	router.Generate(context.Background(), &GenerateRequest{})
+	time.Sleep(20 * time.Millisecond)
+	router.Generate(context.Background(), &GenerateRequest{})
+	if state := router.Health()[0].State; state != CircuitHalfOpen {
+		t.Fatalf("state = %s, want half-open", state)
+	}
+}
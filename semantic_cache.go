@@ -0,0 +1,215 @@
+package deepseek
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/p9966/go-deepseek/vectorstore"
+)
+
+// Metadata keys of the entries a SemanticCache writes to its store.
+const (
+	semanticCacheNamespace = "namespace"
+	semanticCacheQuestion  = "question"
+	semanticCacheResponse  = "response"
+	semanticCacheExpires   = "expires"
+)
+
+// SemanticCache answers chat completions from earlier responses to similar
+// questions. It embeds the final user message of each non-streaming
+// /chat/completions request and, when a stored question of the same
+// namespace is at least Threshold similar, returns the stored response with
+// Cached set and zero usage instead of calling the API. Other responses are
+// stored after a successful call.
+//
+// Only the final user message is compared. By default the namespace also
+// covers the model, the earlier messages including the system prompt and the
+// tools, so requests differing in any of them never share entries. Entries
+// are always scoped by the Authorization header. Errors while embedding or
+// searching fall through to the API.
+type SemanticCache struct {
+	Embedder  Embedder
+	Store     vectorstore.Index                       // Optional: defaults to an in-memory flat index with cosine similarity
+	Threshold float64                                 // Optional: minimum similarity of a hit, defaults to 0.95
+	TTL       time.Duration                           // Optional: lifetime of an entry, forever if zero
+	Namespace func(req *ChatCompletionRequest) string // Optional: partitions the cache, defaults to the model, earlier messages and tools
+
+	once   sync.Once
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// SemanticCacheStats counts cache lookups.
+type SemanticCacheStats struct {
+	Hits   int64
+	Misses int64
+}
+
+// Stats returns the lookups so far.
+func (s *SemanticCache) Stats() SemanticCacheStats {
+	return SemanticCacheStats{Hits: s.hits.Load(), Misses: s.misses.Load()}
+}
+
+// Middleware returns the middleware serving requests from the cache.
+func (s *SemanticCache) Middleware() Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, chatCompletionSuffix) || req.Body == nil {
+				return next.Do(req)
+			}
+			body, err := io.ReadAll(req.Body)
+			req.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			var chat struct {
+				ChatCompletionRequest
+				Stream bool `json:"stream"`
+			}
+			if json.Unmarshal(body, &chat) != nil || chat.Stream || len(chat.Messages) == 0 {
+				return next.Do(req)
+			}
+			last := chat.Messages[len(chat.Messages)-1]
+			if last.Role != ChatMessageRoleUser || strings.TrimSpace(last.Content) == "" {
+				return next.Do(req)
+			}
+
+			namespace := s.namespace(&chat.ChatCompletionRequest)
+			if auth := req.Header.Get("Authorization"); auth != "" {
+				namespace = credentialKey(namespace, auth)
+			}
+			vector, cached := s.lookup(req, namespace, last.Content)
+			if cached != nil {
+				s.hits.Add(1)
+				return replayResponse(req, cachedEntry{ContentType: "application/json", Body: cached}), nil
+			}
+			s.misses.Add(1)
+
+			resp, err := next.Do(req)
+			if err != nil || resp.StatusCode != http.StatusOK || vector == nil {
+				return resp, err
+			}
+			respBody, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(respBody))
+			s.store(namespace, last.Content, vector, respBody)
+			return resp, nil
+		})
+	}
+}
+
+func (s *SemanticCache) init() {
+	s.once.Do(func() {
+		if s.Store == nil {
+			s.Store = vectorstore.NewFlat(vectorstore.Cosine)
+		}
+	})
+}
+
+func (s *SemanticCache) namespace(req *ChatCompletionRequest) string {
+	if s.Namespace != nil {
+		return s.Namespace(req)
+	}
+	scope, _ := json.Marshal(struct {
+		Messages []ChatCompletionMessage `json:"messages"`
+		Tools    []Tools                 `json:"tools"`
+	}{req.Messages[:len(req.Messages)-1], req.Tools})
+	sum := sha256.Sum256(scope)
+	return req.Model + ":" + hex.EncodeToString(sum[:])
+}
+
+// lookup embeds question and returns its vector and the best live cached
+// response in namespace, if any.
+func (s *SemanticCache) lookup(req *http.Request, namespace, question string) ([]float64, []byte) {
+	s.init()
+	vectors, err := s.Embedder.Embed(req.Context(), []string{question})
+	if err != nil || len(vectors) != 1 {
+		return nil, nil
+	}
+	threshold := s.Threshold
+	if threshold == 0 {
+		threshold = 0.95
+	}
+	now := time.Now().UnixNano()
+	results, err := s.Store.Search(vectorstore.Query{
+		Vector:   vectors[0],
+		TopK:     1,
+		MinScore: &threshold,
+		Filter: vectorstore.And(vectorstore.Eq(semanticCacheNamespace, namespace), func(metadata map[string]any) bool {
+			expires, ok := int64Value(metadata[semanticCacheExpires])
+			return !ok || expires > now
+		}),
+	})
+	if err != nil || len(results) == 0 {
+		return vectors[0], nil
+	}
+	response, _ := results[0].Metadata[semanticCacheResponse].(string)
+	return vectors[0], []byte(response)
+}
+
+func (s *SemanticCache) store(namespace, question string, vector []float64, response []byte) {
+	var parsed ChatCompletionResponse
+	if json.Unmarshal(response, &parsed) != nil || len(parsed.Choices) == 0 {
+		return
+	}
+	metadata := map[string]any{
+		semanticCacheNamespace: namespace,
+		semanticCacheQuestion:  question,
+		semanticCacheResponse:  string(response),
+	}
+	if s.TTL > 0 {
+		metadata[semanticCacheExpires] = time.Now().Add(s.TTL).UnixNano()
+	}
+	id := sha256.Sum256([]byte(namespace + "\x00" + question))
+	_ = s.Store.Upsert(vectorstore.Record{ID: hex.EncodeToString(id[:]), Vector: vector, Metadata: metadata})
+}
+
+// Purge deletes the expired entries of the default in-memory store. Entries
+// of other stores are skipped by lookups once expired but are not deleted.
+func (s *SemanticCache) Purge() int {
+	s.init()
+	flat, ok := s.Store.(*vectorstore.Flat)
+	if !ok || flat.Len() == 0 {
+		return 0
+	}
+	now := time.Now().UnixNano()
+	results, err := flat.Search(vectorstore.Query{
+		Vector: make([]float64, flat.Dimension()),
+		Filter: func(metadata map[string]any) bool {
+			expires, ok := int64Value(metadata[semanticCacheExpires])
+			return ok && expires <= now
+		},
+	})
+	if err != nil {
+		return 0
+	}
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.ID
+	}
+	return flat.Delete(ids...)
+}
+
+// int64Value reads a number that may have become a float64 in a snapshot.
+func int64Value(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,141 @@
+package deepseek
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// letterEmbedder embeds texts as letter frequencies, so rephrasings with the
+// same letters are near-duplicates.
+type letterEmbedder struct{}
+
+func (letterEmbedder) Embed(ctx context.Context, inputs []string) ([][]float64, error) {
+	out := make([][]float64, len(inputs))
+	for i, input := range inputs {
+		v := make([]float64, 26)
+		for _, r := range strings.ToLower(input) {
+			if r >= 'a' && r <= 'z' {
+				v[r-'a']++
+			}
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func TestSemanticCache(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"id":"1","model":"deepseek-chat","choices":[{"index":0,"message":{"role":"assistant","content":"Paris"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":10,"completion_tokens":1,"total_tokens":11}}`))
+	}))
+	defer server.Close()
+
+	cache := &SemanticCache{Embedder: letterEmbedder{}, TTL: time.Hour}
+	client := &Client{BaseUrl: server.URL}
+	client.Use(cache.Middleware())
+
+	ask := func(model, question string) *ChatCompletionResponse {
+		t.Helper()
+		resp, err := client.CreateChatCompletion(context.Background(), &ChatCompletionRequest{
+			Model:    model,
+			Messages: []ChatCompletionMessage{{Role: ChatMessageRoleUser, Content: question}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	first := ask(DeepSeekChat, "What is the capital of France?")
+	if first.Cached || first.Usage.TotalTokens != 11 {
+		t.Fatalf("first response cached=%v usage=%+v", first.Cached, first.Usage)
+	}
+
+	second := ask(DeepSeekChat, "what is the capital of france")
+	if !second.Cached || second.Usage.TotalTokens != 0 || second.Choices[0].Message.Content != "Paris" {
+		t.Fatalf("second response cached=%v usage=%+v", second.Cached, second.Usage)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("calls = %d, want 1", calls.Load())
+	}
+
+	// Other models and different questions miss.
+	ask(DeepSeekReasoner, "What is the capital of France?")
+	ask(DeepSeekChat, "How many legs does a spider have?")
+	if calls.Load() != 3 {
+		t.Fatalf("calls = %d, want 3", calls.Load())
+	}
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 3 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestSemanticCacheExpires(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	cache := &SemanticCache{Embedder: letterEmbedder{}, TTL: time.Nanosecond}
+	client := &Client{BaseUrl: server.URL}
+	client.Use(cache.Middleware())
+	req := &ChatCompletionRequest{Model: DeepSeekChat, Messages: []ChatCompletionMessage{{Role: ChatMessageRoleUser, Content: "hello"}}}
+	for i := 0; i < 2; i++ {
+		if _, err := client.CreateChatCompletion(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("calls = %d, want 2", calls.Load())
+	}
+	if n := cache.Purge(); n != 1 {
+		t.Fatalf("purged = %d", n)
+	}
+}
+
+func TestSemanticCacheScopesBySystemPromptAndCredential(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	cache := &SemanticCache{Embedder: letterEmbedder{}}
+	ask := func(token, system string) {
+		t.Helper()
+		client := &Client{BaseUrl: server.URL, AuthToken: token}
+		client.Use(cache.Middleware())
+		_, err := client.CreateChatCompletion(context.Background(), &ChatCompletionRequest{
+			Model: DeepSeekChat,
+			Messages: []ChatCompletionMessage{
+				{Role: ChatMessageRoleSystem, Content: system},
+				{Role: ChatMessageRoleUser, Content: "hello"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ask("key-a", "Answer in English.")
+	ask("key-a", "Answer in French.")
+	ask("key-b", "Answer in English.")
+	if calls.Load() != 3 {
+		t.Fatalf("calls = %d, want 3", calls.Load())
+	}
+	ask("key-a", "Answer in English.")
+	if calls.Load() != 3 {
+		t.Fatalf("calls = %d, want a cache hit", calls.Load())
+	}
+}
@@ -0,0 +1,170 @@
+package textsplitter
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// languageSeparators cut source code at declarations before falling back to
+// blank lines, lines and words.
+var languageSeparators = map[string][]string{
+	"go":         {"\nfunc ", "\ntype ", "\nvar ", "\nconst ", "\n\n", "\n", " "},
+	"python":     {"\nclass ", "\ndef ", "\n    def ", "\n\n", "\n", " "},
+	"javascript": {"\nfunction ", "\nclass ", "\nexport ", "\nconst ", "\nlet ", "\n\n", "\n", " "},
+	"typescript": {"\nfunction ", "\nclass ", "\ninterface ", "\ntype ", "\nexport ", "\nconst ", "\nlet ", "\n\n", "\n", " "},
+	"java":       {"\nclass ", "\ninterface ", "\n    public ", "\n    protected ", "\n    private ", "\n\n", "\n", " "},
+	"rust":       {"\nfn ", "\npub fn ", "\nstruct ", "\npub struct ", "\nenum ", "\nimpl ", "\ntrait ", "\nmod ", "\n\n", "\n", " "},
+}
+
+// LanguageSeparators returns Recursive separators for source code in
+// language: "go", "python", "javascript", "typescript", "java" or "rust".
+// Unknown languages get the separators of plain text.
+func LanguageSeparators(language string) []string {
+	if seps, ok := languageSeparators[strings.ToLower(language)]; ok {
+		return append([]string(nil), seps...)
+	}
+	return append([]string(nil), DefaultSeparators...)
+}
+
+// Code cuts source code like Recursive, preferring to cut between top-level
+// declarations.
+type Code struct {
+	Language string     // e.g. "go" or "python", see LanguageSeparators
+	Size     int        // Optional: chunk size in units of Length, defaults to 1000
+	Overlap  int        // Optional: size shared by consecutive chunks
+	Length   LengthFunc // Optional: defaults to RuneCount
+}
+
+func (s Code) Split(text string) []Chunk {
+	return Recursive{Size: s.Size, Overlap: s.Overlap, Separators: LanguageSeparators(s.Language), Length: s.Length}.Split(text)
+}
+
+// Go cuts Go source at its top-level declarations, doc comments included, and
+// packs consecutive small declarations together up to Size. Declarations
+// larger than Size are split by blank lines and lines. Each chunk records the
+// declared names under "symbol", such as "(*Client).Do" or "Options". Source
+// that does not parse is split like Code.
+type Go struct {
+	Size    int        // Optional: chunk size in units of Length, defaults to 1000
+	Overlap int        // Optional: size shared by consecutive chunks of a large declaration
+	Length  LengthFunc // Optional: defaults to RuneCount
+}
+
+func (s Go) Split(text string) []Chunk {
+	size, _ := normalize(s.Size, s.Overlap)
+	length := s.Length
+	if length == nil {
+		length = RuneCount
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", text, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil || len(file.Decls) == 0 {
+		return Code{Language: "go", Size: s.Size, Overlap: s.Overlap, Length: s.Length}.Split(text)
+	}
+
+	// Each declaration owns the text from its doc comment up to the next
+	// declaration; the package clause and anything before the first
+	// declaration form the header.
+	type decl struct {
+		span
+		symbol string
+	}
+	decls := []decl{{span: span{0, 0}}}
+	for _, d := range file.Decls {
+		start := fset.Position(declStart(d)).Offset
+		decls[len(decls)-1].end = start
+		decls = append(decls, decl{span: span{start, len(text)}, symbol: declSymbol(d)})
+	}
+
+	inner := Recursive{Size: s.Size, Overlap: s.Overlap, Separators: []string{"\n\n", "\n", " "}, Length: length}
+	var chunks []Chunk
+	var group []decl
+	groupSize := 0
+	flush := func() {
+		if len(group) == 0 {
+			return
+		}
+		var symbols []string
+		for _, d := range group {
+			if d.symbol != "" {
+				symbols = append(symbols, d.symbol)
+			}
+		}
+		if c, ok := trimmed(text, group[0].start, group[len(group)-1].end); ok {
+			chunks = append(chunks, withMetadata([]Chunk{c}, "symbol", strings.Join(symbols, ", "))...)
+		}
+		group, groupSize = nil, 0
+	}
+	for _, d := range decls {
+		n := length(text[d.start:d.end])
+		if n > size {
+			flush()
+			chunks = append(chunks, withMetadata(inner.splitRange(text, d.start, d.end), "symbol", d.symbol)...)
+			continue
+		}
+		if groupSize+n > size {
+			flush()
+		}
+		group = append(group, d)
+		groupSize += n
+	}
+	flush()
+	return chunks
+}
+
+func declStart(d ast.Decl) token.Pos {
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		if d.Doc != nil {
+			return d.Doc.Pos()
+		}
+	case *ast.GenDecl:
+		if d.Doc != nil {
+			return d.Doc.Pos()
+		}
+	}
+	return d.Pos()
+}
+
+func declSymbol(d ast.Decl) string {
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		if d.Recv == nil || len(d.Recv.List) == 0 {
+			return d.Name.Name
+		}
+		return "(" + receiverType(d.Recv.List[0].Type) + ")." + d.Name.Name
+	case *ast.GenDecl:
+		var names []string
+		for _, spec := range d.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, spec.Name.Name)
+			case *ast.ValueSpec:
+				for _, name := range spec.Names {
+					if name.Name != "_" {
+						names = append(names, name.Name)
+					}
+				}
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	return ""
+}
+
+func receiverType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return "*" + receiverType(t.X)
+	case *ast.IndexExpr:
+		return receiverType(t.X)
+	case *ast.IndexListExpr:
+		return receiverType(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
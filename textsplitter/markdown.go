@@ -0,0 +1,78 @@
+package textsplitter
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MarkdownSeparators split a Markdown section by paragraph, line, sentence
+// and word.
+var MarkdownSeparators = append([]string{"\n\n", "\n- ", "\n* "}, DefaultSeparators[1:]...)
+
+var markdownHeading = regexp.MustCompile(`^(#{1,6})[ \t]+(.*?)[ \t#]*$`)
+
+// Markdown cuts a Markdown document at its headings, so no chunk spans two
+// sections, and splits sections larger than Size like Recursive. Each chunk
+// records its heading path, such as "Usage > Streaming", under "heading".
+// Headings inside fenced code blocks are ignored.
+type Markdown struct {
+	Size    int        // Optional: chunk size in units of Length, defaults to 1000
+	Overlap int        // Optional: size shared by consecutive chunks of a section
+	Length  LengthFunc // Optional: defaults to RuneCount
+}
+
+func (s Markdown) Split(text string) []Chunk {
+	inner := Recursive{Size: s.Size, Overlap: s.Overlap, Separators: MarkdownSeparators, Length: s.Length}
+
+	var chunks []Chunk
+	var headings []string
+	sectionStart, path := 0, ""
+	flush := func(end int) {
+		chunks = append(chunks, withMetadata(inner.splitRange(text, sectionStart, end), "heading", path)...)
+	}
+
+	fence := ""
+	for offset := 0; offset < len(text); {
+		line := text[offset:]
+		next := len(text)
+		if i := strings.IndexByte(line, '\n'); i >= 0 {
+			line, next = line[:i], offset+i+1
+		}
+		trimmedLine := strings.TrimSpace(line)
+
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmedLine, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmedLine, "```"), strings.HasPrefix(trimmedLine, "~~~"):
+			fence = trimmedLine[:3]
+		default:
+			if m := markdownHeading.FindStringSubmatch(strings.TrimRight(line, "\r")); m != nil {
+				flush(offset)
+				level := len(m[1])
+				if len(headings) >= level {
+					headings = headings[:level-1]
+				}
+				for len(headings) < level-1 {
+					headings = append(headings, "")
+				}
+				headings = append(headings, m[2])
+				sectionStart, path = offset, joinHeadings(headings)
+			}
+		}
+		offset = next
+	}
+	flush(len(text))
+	return chunks
+}
+
+func joinHeadings(headings []string) string {
+	var parts []string
+	for _, h := range headings {
+		if h != "" {
+			parts = append(parts, h)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
@@ -0,0 +1,289 @@
+// Package textsplitter cuts documents into chunks small enough to embed.
+// Every chunk records its byte offsets in the source, so answers built on it
+// can cite the exact passage.
+package textsplitter
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	deepseek "github.com/p9966/go-deepseek"
+)
+
+const defaultChunkSize = 1000
+
+// Chunk is a piece of a document.
+type Chunk struct {
+	Text     string            `json:"text"`
+	Start    int               `json:"start"`              // byte offset of Text in the source
+	End      int               `json:"end"`                // byte offset just past Text, so source[Start:End] == Text
+	Metadata map[string]string `json:"metadata,omitempty"` // e.g. "heading" for Markdown, "symbol" for Go
+}
+
+// Splitter cuts a document into chunks, in document order.
+type Splitter interface {
+	Split(text string) []Chunk
+}
+
+// LengthFunc measures text in the unit of a splitter's Size.
+type LengthFunc func(text string) int
+
+// RuneCount measures text in characters.
+func RuneCount(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// TokenLength measures text in tokens estimated by counter.
+func TokenLength(counter deepseek.TokenCounter) LengthFunc {
+	return counter.CountTokens
+}
+
+// Characters cuts text into windows of Size characters, each starting
+// Size-Overlap characters after the previous one.
+type Characters struct {
+	Size    int // Optional: characters per chunk, defaults to 1000
+	Overlap int // Optional: characters shared by consecutive chunks
+}
+
+func (s Characters) Split(text string) []Chunk {
+	size, overlap := normalize(s.Size, s.Overlap)
+	offsets := make([]int, 0, len(text)+1)
+	for i := range text {
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(text))
+	runes := len(offsets) - 1
+
+	var chunks []Chunk
+	for first := 0; first < runes; first += size - overlap {
+		last := min(first+size, runes)
+		start, end := offsets[first], offsets[last]
+		chunks = append(chunks, Chunk{Text: text[start:end], Start: start, End: end})
+		if last == runes {
+			break
+		}
+	}
+	return chunks
+}
+
+// Tokens cuts text into chunks of at most Size tokens, breaking between words
+// and between CJK characters.
+type Tokens struct {
+	Size    int                   // Optional: tokens per chunk, defaults to 256
+	Overlap int                   // Optional: tokens shared by consecutive chunks
+	Counter deepseek.TokenCounter // Optional: token estimator, defaults to the DeepSeek Tokenizer
+}
+
+func (s Tokens) Split(text string) []Chunk {
+	if s.Size <= 0 {
+		s.Size = 256
+	}
+	size, overlap := normalize(s.Size, s.Overlap)
+	counter := s.Counter
+	if counter == nil {
+		counter = deepseek.NewTokenizer(deepseek.DeepSeekChat)
+	}
+	return merge(text, words(text, 0, len(text)), size, overlap, counter.CountTokens)
+}
+
+// Recursive cuts text at the first separator that yields pieces of at most
+// Size, moving on to the next separator for pieces that are still too large,
+// and then packs adjacent pieces back together up to Size.
+//
+// Pieces are cut after the leading newlines of a separator, so "\nfunc "
+// starts the next piece with "func " while "\n\n" and ". " end the previous
+// one.
+type Recursive struct {
+	Size       int        // Optional: chunk size in units of Length, defaults to 1000
+	Overlap    int        // Optional: size shared by consecutive chunks
+	Separators []string   // Optional: separators from coarsest to finest, defaults to DefaultSeparators
+	Length     LengthFunc // Optional: defaults to RuneCount
+}
+
+// DefaultSeparators split prose by paragraph, line, sentence and word.
+var DefaultSeparators = []string{"\n\n", "\n", "。", "！", "？", ". ", "! ", "? ", "; ", ", ", " "}
+
+func (s Recursive) Split(text string) []Chunk {
+	return s.splitRange(text, 0, len(text))
+}
+
+func (s Recursive) splitRange(text string, start, end int) []Chunk {
+	size, overlap := normalize(s.Size, s.Overlap)
+	separators := s.Separators
+	if separators == nil {
+		separators = DefaultSeparators
+	}
+	length := s.Length
+	if length == nil {
+		length = RuneCount
+	}
+	pieces := atoms(text, span{start, end}, separators, size, length)
+	return merge(text, pieces, size, overlap, length)
+}
+
+// span is a byte range of the source.
+type span struct {
+	start, end int
+}
+
+// atoms cuts r into pieces of at most size, using the coarsest separators that
+// work. Pieces with no separator left are cut between words, then characters.
+func atoms(text string, r span, separators []string, size int, length LengthFunc) []span {
+	if length(text[r.start:r.end]) <= size {
+		return []span{r}
+	}
+	if len(separators) == 0 {
+		ws := words(text, r.start, r.end)
+		if len(ws) > 1 {
+			var out []span
+			for _, w := range ws {
+				out = append(out, atoms(text, w, nil, size, length)...)
+			}
+			return out
+		}
+		var out []span
+		for i, c := range text[r.start:r.end] {
+			out = append(out, span{r.start + i, r.start + i + utf8.RuneLen(c)})
+		}
+		return out
+	}
+
+	var out []span
+	for _, piece := range cut(text, r, separators[0]) {
+		out = append(out, atoms(text, piece, separators[1:], size, length)...)
+	}
+	return out
+}
+
+// cut splits r at every occurrence of sep.
+func cut(text string, r span, sep string) []span {
+	lead := len(sep) - len(strings.TrimLeft(sep, "\n"))
+	if lead == 0 {
+		lead = len(sep)
+	}
+
+	var out []span
+	start := r.start
+	for from := r.start; from < r.end; {
+		i := strings.Index(text[from:r.end], sep)
+		if i < 0 {
+			break
+		}
+		i += from
+		at := i + lead
+		if at > start && at < r.end {
+			out = append(out, span{start, at})
+			start = at
+		}
+		from = i + len(sep)
+	}
+	return append(out, span{start, r.end})
+}
+
+// words cuts text[start:end] into words with their trailing whitespace, and
+// into single CJK characters, which carry a token each.
+func words(text string, start, end int) []span {
+	var out []span
+	pieceStart := start
+	inSpace := false
+	for i, c := range text[start:end] {
+		i += start
+		switch {
+		case isCJK(c):
+			if i > pieceStart {
+				out = append(out, span{pieceStart, i})
+			}
+			out = append(out, span{i, i + utf8.RuneLen(c)})
+			pieceStart, inSpace = i+utf8.RuneLen(c), false
+		case unicode.IsSpace(c):
+			inSpace = true
+		default:
+			if inSpace && i > pieceStart {
+				out = append(out, span{pieceStart, i})
+				pieceStart = i
+			}
+			inSpace = false
+		}
+	}
+	if pieceStart < end {
+		out = append(out, span{pieceStart, end})
+	}
+	return out
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// merge packs consecutive pieces into chunks of at most size, starting each
+// chunk with up to overlap of the previous one. A single piece larger than
+// size becomes a chunk of its own.
+func merge(text string, pieces []span, size, overlap int, length LengthFunc) []Chunk {
+	lengths := make([]int, len(pieces))
+	for i, p := range pieces {
+		lengths[i] = length(text[p.start:p.end])
+	}
+
+	var chunks []Chunk
+	emit := func(first, last int) {
+		if c, ok := trimmed(text, pieces[first].start, pieces[last].end); ok {
+			chunks = append(chunks, c)
+		}
+	}
+
+	first, total := 0, 0
+	for i := range pieces {
+		if i > first && total+lengths[i] > size {
+			emit(first, i-1)
+			for first < i && (total > overlap || total+lengths[i] > size) {
+				total -= lengths[first]
+				first++
+			}
+		}
+		total += lengths[i]
+	}
+	if first < len(pieces) {
+		emit(first, len(pieces)-1)
+	}
+	return chunks
+}
+
+// trimmed returns text[start:end] without surrounding whitespace, or false if
+// nothing is left.
+func trimmed(text string, start, end int) (Chunk, bool) {
+	s := text[start:end]
+	trimmedLeft := strings.TrimLeftFunc(s, unicode.IsSpace)
+	start += len(s) - len(trimmedLeft)
+	s = strings.TrimRightFunc(trimmedLeft, unicode.IsSpace)
+	if s == "" {
+		return Chunk{}, false
+	}
+	return Chunk{Text: s, Start: start, End: start + len(s)}, true
+}
+
+func normalize(size, overlap int) (int, int) {
+	if size <= 0 {
+		size = defaultChunkSize
+	}
+	if overlap < 0 {
+		overlap = 0
+	}
+	if overlap >= size {
+		overlap = size / 2
+	}
+	return size, overlap
+}
+
+func withMetadata(chunks []Chunk, key, value string) []Chunk {
+	if value == "" {
+		return chunks
+	}
+	for i := range chunks {
+		if chunks[i].Metadata == nil {
+			chunks[i].Metadata = make(map[string]string)
+		}
+		chunks[i].Metadata[key] = value
+	}
+	return chunks
+}
@@ -0,0 +1,162 @@
+package textsplitter
+
+import (
+	"strings"
+	"testing"
+
+	deepseek "github.com/p9966/go-deepseek"
+)
+
+// checkOffsets verifies that every chunk is the source text at its offsets.
+func checkOffsets(t *testing.T, text string, chunks []Chunk) {
+	t.Helper()
+	if len(chunks) == 0 {
+		t.Fatal("no chunks")
+	}
+	for i, c := range chunks {
+		if text[c.Start:c.End] != c.Text {
+			t.Fatalf("chunk %d: text %q does not match source[%d:%d] %q", i, c.Text, c.Start, c.End, text[c.Start:c.End])
+		}
+	}
+}
+
+func texts(chunks []Chunk) []string {
+	out := make([]string, len(chunks))
+	for i, c := range chunks {
+		out[i] = c.Text
+	}
+	return out
+}
+
+func TestCharacters(t *testing.T) {
+	text := "你好世界abcdef"
+	chunks := Characters{Size: 4, Overlap: 1}.Split(text)
+	checkOffsets(t, text, chunks)
+	want := []string{"你好世界", "界abc", "cdef"}
+	if got := texts(chunks); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("chunks = %q, want %q", got, want)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	text := "one two three four five six seven"
+	counter := deepseek.TokenCounterFunc(func(s string) int { return len(strings.Fields(s)) })
+	chunks := Tokens{Size: 3, Overlap: 1, Counter: counter}.Split(text)
+	checkOffsets(t, text, chunks)
+	want := []string{"one two three", "three four five", "five six seven"}
+	if got := texts(chunks); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("chunks = %q, want %q", got, want)
+	}
+
+	chunks = Tokens{Size: 50}.Split(strings.Repeat("token estimation ", 200))
+	for _, c := range chunks {
+		if n := deepseek.CountTokens(deepseek.DeepSeekChat, c.Text); n > 55 {
+			t.Fatalf("chunk has %d tokens", n)
+		}
+	}
+}
+
+func TestRecursivePrefersCoarseSeparators(t *testing.T) {
+	text := "First paragraph is here.\n\nSecond one. It has two sentences.\n\nThird."
+	chunks := Recursive{Size: 30}.Split(text)
+	checkOffsets(t, text, chunks)
+	want := []string{"First paragraph is here.", "Second one.", "It has two sentences.\n\nThird."}
+	if got := texts(chunks); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("chunks = %q, want %q", got, want)
+	}
+
+	for _, c := range (Recursive{Size: 10, Overlap: 4}).Split(strings.Repeat("abc ", 30)) {
+		if len(c.Text) > 10 {
+			t.Fatalf("chunk %q exceeds size", c.Text)
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	text := `Intro text.
+
+# Usage
+
+Install it.
+
+## Streaming
+
+Read the stream.
+
+` + "```sh\n# not a heading\n```" + `
+
+# API
+Reference.
+`
+	chunks := Markdown{Size: 200}.Split(text)
+	checkOffsets(t, text, chunks)
+	want := []struct{ heading, prefix string }{
+		{"", "Intro text."},
+		{"Usage", "# Usage"},
+		{"Usage > Streaming", "## Streaming"},
+		{"API", "# API"},
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("chunks = %q", texts(chunks))
+	}
+	for i, w := range want {
+		if chunks[i].Metadata["heading"] != w.heading || !strings.HasPrefix(chunks[i].Text, w.prefix) {
+			t.Fatalf("chunk %d = %q (heading %q), want heading %q", i, chunks[i].Text, chunks[i].Metadata["heading"], w.heading)
+		}
+	}
+	if !strings.Contains(chunks[2].Text, "# not a heading") {
+		t.Fatalf("fenced code was split off: %q", chunks[2].Text)
+	}
+}
+
+const goSource = `package sample
+
+import "fmt"
+
+// Greeter says hello.
+type Greeter struct{ Name string }
+
+// Greet prints a greeting.
+func (g *Greeter) Greet() {
+	fmt.Println("hello", g.Name)
+}
+
+const answer = 42
+
+func long() {
+	a := 1
+	b := 2
+
+	c := a + b
+	fmt.Println(c)
+}
+`
+
+func TestGo(t *testing.T) {
+	chunks := Go{Size: 60}.Split(goSource)
+	checkOffsets(t, goSource, chunks)
+
+	symbols := map[string]string{}
+	for _, c := range chunks {
+		symbols[c.Metadata["symbol"]] += c.Text
+	}
+	if !strings.HasPrefix(symbols["Greeter"], "// Greeter says hello.") {
+		t.Fatalf("Greeter chunk = %q", symbols["Greeter"])
+	}
+	if !strings.HasPrefix(symbols["(*Greeter).Greet"], "// Greet prints") {
+		t.Fatalf("Greet chunk = %q", symbols["(*Greeter).Greet"])
+	}
+	var longParts int
+	for _, c := range chunks {
+		if c.Metadata["symbol"] == "long" {
+			longParts++
+		}
+	}
+	if longParts < 2 {
+		t.Fatalf("large declaration was not split: %q", texts(chunks))
+	}
+
+	// Unparsable source falls back to separators.
+	broken := "func a() {\n\tx := 1\n\nfunc b() {\n"
+	checkOffsets(t, broken, Go{Size: 12}.Split(broken))
+}
@@ -0,0 +1,135 @@
+package deepseek
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// fimSpecialTokens is the number of sentinel tokens wrapped around a FIM
+// prompt and suffix.
+const fimSpecialTokens = 3
+
+// Tokenizer is an offline token estimator calibrated for a model family. It
+// splits text the way byte-level BPE pre-tokenizers do and applies per-class
+// rates approximating the DeepSeek V3 and Qwen vocabularies. Counts are an
+// estimate for budgeting; the Usage returned by the API is authoritative.
+type Tokenizer struct {
+	family        string
+	cjkPerChar    float64 // tokens per CJK character
+	charsPerToken float64 // letters per token inside a word
+	digitGroup    int     // digits merged into a single token
+}
+
+var (
+	deepSeekTokenizer = &Tokenizer{family: "deepseek", cjkPerChar: 0.6, charsPerToken: 6, digitGroup: 3}
+	qwenTokenizer     = &Tokenizer{family: "qwen", cjkPerChar: 0.65, charsPerToken: 5.5, digitGroup: 1}
+)
+
+// NewTokenizer returns the estimator for the family of model. Unknown models
+// use the DeepSeek V3 calibration.
+func NewTokenizer(model string) *Tokenizer {
+	name := strings.ToLower(model)
+	if strings.HasPrefix(name, "qwen") || strings.HasPrefix(name, "qwq") {
+		return qwenTokenizer
+	}
+	return deepSeekTokenizer
+}
+
+// CountTokens estimates the tokens of text for model.
+func CountTokens(model, text string) int {
+	return NewTokenizer(model).CountTokens(text)
+}
+
+// Family returns the model family the tokenizer is calibrated for.
+func (t *Tokenizer) Family() string {
+	return t.family
+}
+
+// CountTokens estimates the number of tokens in text.
+func (t *Tokenizer) CountTokens(text string) int {
+	var total float64
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		j := i + 1
+		switch {
+		case isCJK(r):
+			total += t.cjkPerChar
+		case unicode.IsLetter(r):
+			for j < len(runes) && unicode.IsLetter(runes[j]) && !isCJK(runes[j]) {
+				j++
+			}
+			total += math.Ceil(float64(j-i) / t.charsPerToken)
+		case unicode.IsDigit(r):
+			for j < len(runes) && unicode.IsDigit(runes[j]) {
+				j++
+			}
+			total += math.Ceil(float64(j-i) / float64(t.digitGroup))
+		case r == '\n':
+			for j < len(runes) && runes[j] == '\n' {
+				j++
+			}
+			total++
+		case unicode.IsSpace(r):
+			// A single space is merged into the following word.
+			for j < len(runes) && unicode.IsSpace(runes[j]) && runes[j] != '\n' {
+				j++
+			}
+			if j-i > 1 {
+				total++
+			}
+		default:
+			for j < len(runes) && unicode.IsPunct(runes[j]) && j-i < 2 {
+				j++
+			}
+			total++
+		}
+		i = j
+	}
+	return int(math.Ceil(total))
+}
+
+// CountMessages estimates the prompt tokens of a chat request made of msgs and
+// tools, including the template tokens around every message.
+func (t *Tokenizer) CountMessages(msgs []ChatCompletionMessage, tools []Tools) int {
+	return countMessageTokens(t, msgs) + countToolTokens(t, tools)
+}
+
+// CountRequest estimates the prompt tokens of req.
+func (t *Tokenizer) CountRequest(req *ChatCompletionRequest) int {
+	return t.CountMessages(req.Messages, req.Tools)
+}
+
+// CountFIM estimates the prompt tokens of a FIM completion request.
+func (t *Tokenizer) CountFIM(req *FINCompletionRequest) int {
+	return fimSpecialTokens + t.CountTokens(req.Prompt) + t.CountTokens(req.Suffix)
+}
+
+func countMessageTokens(counter TokenCounter, msgs []ChatCompletionMessage) int {
+	total := 0
+	for _, msg := range msgs {
+		total += perMessageTokens + counter.CountTokens(msg.Content)
+		for _, tc := range msg.ToolCalls {
+			total += counter.CountTokens(tc.Function.Name) + counter.CountTokens(tc.Function.Arguments)
+		}
+	}
+	return total
+}
+
+func countToolTokens(counter TokenCounter, tools []Tools) int {
+	if len(tools) == 0 {
+		return 0
+	}
+	buf, err := json.Marshal(tools)
+	if err != nil {
+		return 0
+	}
+	return counter.CountTokens(string(buf))
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303f) || (r >= 0xff00 && r <= 0xffef)
+}
@@ -0,0 +1,78 @@
+package deepseek
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTokenizerCountTokens(t *testing.T) {
+	tests := []struct {
+		model string
+		text  string
+		want  int
+	}{
+		{DeepSeekChat, "Hello, world!", 4},
+		{DeepSeekChat, "The quick brown fox jumps over the lazy dog.", 10},
+		{DeepSeekChat, "你好，世界", 3},
+		{DeepSeekChat, "12345", 2},
+		{QWEN3_32B, "12345", 5},
+		{QWEN3_32B, "", 0},
+	}
+	for _, tt := range tests {
+		if got := CountTokens(tt.model, tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q, %q) = %d, want %d", tt.model, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTokenizerCountPrompts(t *testing.T) {
+	tok := NewTokenizer(DeepSeekChat)
+	tools := []Tools{{Type: "function", Function: Function{Name: "get_weather", Description: "Get the weather of a city"}}}
+	toolJSON, _ := json.Marshal(tools)
+	call := ToolCall{Id: "call_1", Type: "function", Function: FunctionCall{Name: "get_weather", Arguments: `{"city":"Hangzhou"}`}}
+
+	tests := []struct {
+		name string
+		got  int
+		want int
+	}{
+		{
+			"messages",
+			tok.CountMessages([]ChatCompletionMessage{{Role: ChatMessageRoleSystem, Content: "Be brief."}, {Role: ChatMessageRoleUser, Content: "Hello"}}, nil),
+			2*perMessageTokens + tok.CountTokens("Be brief.") + tok.CountTokens("Hello"),
+		},
+		{
+			"empty messages",
+			tok.CountMessages([]ChatCompletionMessage{{}, {}, {}}, nil),
+			3 * perMessageTokens,
+		},
+		{
+			"tool calls",
+			tok.CountMessages([]ChatCompletionMessage{{Role: ChatMessageRoleAssistant, ToolCalls: []ToolCall{call}}}, nil),
+			perMessageTokens + tok.CountTokens("get_weather") + tok.CountTokens(`{"city":"Hangzhou"}`),
+		},
+		{
+			"tools",
+			tok.CountRequest(&ChatCompletionRequest{Messages: []ChatCompletionMessage{{Role: ChatMessageRoleUser, Content: "Weather?"}}, Tools: tools}),
+			perMessageTokens + tok.CountTokens("Weather?") + tok.CountTokens(string(toolJSON)),
+		},
+		{
+			"fim",
+			tok.CountFIM(&FINCompletionRequest{Prompt: "def add(a, b):", Suffix: "    return c"}),
+			fimSpecialTokens + tok.CountTokens("def add(a, b):") + tok.CountTokens("    return c"),
+		},
+		{
+			"empty fim",
+			tok.CountFIM(&FINCompletionRequest{}),
+			fimSpecialTokens,
+		},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %d tokens, want %d", tt.name, tt.got, tt.want)
+		}
+	}
+	if tok.CountMessages(nil, tools) <= 0 {
+		t.Error("tools added no tokens")
+	}
+}
@@ -0,0 +1,155 @@
+package deepseek
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// ToolCall converts an Ollama tool call into the OpenAI-style ToolCall, whose
+// arguments are a JSON string. Ollama does not assign call IDs, so one is
+// derived from the index.
+func (t OllamaTool) ToolCall(index int) (ToolCall, error) {
+	args := t.Function.Arguments
+	if args == nil {
+		args = map[string]any{}
+	}
+	buf, err := json.Marshal(args)
+	if err != nil {
+		return ToolCall{}, err
+	}
+	return ToolCall{
+		Index:    index,
+		Id:       "call_" + strconv.Itoa(index),
+		Type:     "function",
+		Function: FunctionCall{Name: t.Function.Name, Arguments: string(buf)},
+	}, nil
+}
+
+// OllamaToolFromToolCall converts an OpenAI-style ToolCall into an Ollama tool
+// call.
+func OllamaToolFromToolCall(tc ToolCall) (OllamaTool, error) {
+	args := map[string]any{}
+	if tc.Function.Arguments != "" {
+		if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+			return OllamaTool{}, fmt.Errorf("invalid arguments for %s: %w", tc.Function.Name, err)
+		}
+	}
+	var call OllamaTool
+	call.Function.Name, call.Function.Arguments = tc.Function.Name, args
+	return call, nil
+}
+
+// ToolHandler runs a tool with its JSON encoded arguments and returns the
+// result sent back to the model.
+type ToolHandler func(ctx context.Context, arguments json.RawMessage) (string, error)
+
+type registeredTool struct {
+	function Function
+	handler  ToolHandler
+}
+
+// ToolRegistry maps tool definitions to Go handlers, so the same functions
+// serve tool calls from CreateChatCompletion and CreateOllamaChatCompletion.
+// It is safe for concurrent use.
+type ToolRegistry struct {
+	mu    sync.RWMutex
+	tools map[string]registeredTool
+	order []string
+}
+
+// NewToolRegistry creates an empty registry.
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{tools: make(map[string]registeredTool)}
+}
+
+// Register adds a tool. Registering a name twice replaces the earlier tool.
+func (r *ToolRegistry) Register(function Function, handler ToolHandler) error {
+	if function.Name == "" {
+		return errors.New("tool name can not be empty")
+	}
+	if handler == nil {
+		return errors.New("tool handler can not be nil")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tools[function.Name]; !ok {
+		r.order = append(r.order, function.Name)
+	}
+	r.tools[function.Name] = registeredTool{function: function, handler: handler}
+	return nil
+}
+
+// RegisterTool registers fn as a tool whose arguments are decoded into T.
+func RegisterTool[T any](r *ToolRegistry, function Function, fn func(ctx context.Context, args T) (string, error)) error {
+	return r.Register(function, func(ctx context.Context, arguments json.RawMessage) (string, error) {
+		var args T
+		if len(arguments) > 0 {
+			if err := json.Unmarshal(arguments, &args); err != nil {
+				return "", fmt.Errorf("invalid arguments for %s: %w", function.Name, err)
+			}
+		}
+		return fn(ctx, args)
+	})
+}
+
+// Tools returns the registered tool definitions in registration order, for
+// the Tools field of both chat requests.
+func (r *ToolRegistry) Tools() []Tools {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tools := make([]Tools, 0, len(r.order))
+	for _, name := range r.order {
+		tools = append(tools, Tools{Type: "function", Function: r.tools[name].function})
+	}
+	return tools
+}
+
+// Call runs the tool called name.
+func (r *ToolRegistry) Call(ctx context.Context, name string, arguments json.RawMessage) (string, error) {
+	r.mu.RLock()
+	tool, ok := r.tools[name]
+	r.mu.RUnlock()
+	if !ok {
+		return "", errors.New("unknown tool: " + name)
+	}
+	return tool.handler(ctx, arguments)
+}
+
+// RunToolCalls runs the tool calls of an assistant message and returns the
+// tool messages answering them. A failing tool reports its error to the model.
+func (r *ToolRegistry) RunToolCalls(ctx context.Context, calls []ToolCall) []ChatCompletionMessage {
+	msgs := make([]ChatCompletionMessage, 0, len(calls))
+	for _, call := range calls {
+		result := r.run(ctx, call.Function.Name, json.RawMessage(call.Function.Arguments))
+		msgs = append(msgs, ChatCompletionMessage{Role: ChatMessageRoleTool, Content: result, ToolCallID: call.Id})
+	}
+	return msgs
+}
+
+// RunOllamaToolCalls runs the tool calls of an Ollama assistant message and
+// returns the tool messages answering them.
+func (r *ToolRegistry) RunOllamaToolCalls(ctx context.Context, calls []OllamaTool) []OllamaChatMessage {
+	msgs := make([]OllamaChatMessage, 0, len(calls))
+	for i, call := range calls {
+		var result string
+		if tc, err := call.ToolCall(i); err != nil {
+			result = "error: " + err.Error()
+		} else {
+			result = r.run(ctx, call.Function.Name, json.RawMessage(tc.Function.Arguments))
+		}
+		msgs = append(msgs, OllamaChatMessage{Role: ChatMessageRoleTool, Content: result, ToolName: call.Function.Name})
+	}
+	return msgs
+}
+
+func (r *ToolRegistry) run(ctx context.Context, name string, arguments json.RawMessage) string {
+	result, err := r.Call(ctx, name, arguments)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return result
+}
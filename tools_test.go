@@ -0,0 +1,44 @@
+package deepseek
+
+import (
+	"context"
+	"testing"
+)
+
+func TestToolRegistryServesBothBackends(t *testing.T) {
+	type args struct {
+		Location string `json:"location"`
+	}
+	registry := NewToolRegistry()
+	err := RegisterTool(registry, Function{Name: "get_weather", Description: "weather"}, func(ctx context.Context, a args) (string, error) {
+		return "sunny in " + a.Location, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ollamaCall OllamaTool
+	ollamaCall.Function.Name = "get_weather"
+	ollamaCall.Function.Arguments = map[string]any{"location": "Chengdu"}
+	ollamaResults := registry.RunOllamaToolCalls(context.Background(), []OllamaTool{ollamaCall})
+	if ollamaResults[0].Content != "sunny in Chengdu" || ollamaResults[0].ToolName != "get_weather" || ollamaResults[0].Role != ChatMessageRoleTool {
+		t.Fatalf("unexpected ollama result: %+v", ollamaResults[0])
+	}
+
+	call, err := ollamaCall.ToolCall(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := registry.RunToolCalls(context.Background(), []ToolCall{call, {Id: "x", Function: FunctionCall{Name: "missing"}}})
+	if results[0].Content != "sunny in Chengdu" || results[0].ToolCallID != call.Id {
+		t.Fatalf("unexpected result: %+v", results[0])
+	}
+	if results[1].Content != "error: unknown tool: missing" {
+		t.Fatalf("unexpected error result: %+v", results[1])
+	}
+
+	back, err := OllamaToolFromToolCall(call)
+	if err != nil || back.Function.Arguments["location"] != "Chengdu" {
+		t.Fatalf("round trip failed: %+v, %v", back, err)
+	}
+}
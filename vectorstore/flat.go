@@ -0,0 +1,202 @@
+package vectorstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+var _ Index = (*Flat)(nil)
+
+// Flat is an exact index that compares the query with every record. It is
+// the right choice up to a few hundred thousand vectors. It is safe for
+// concurrent use.
+type Flat struct {
+	metric Metric
+
+	mu        sync.RWMutex
+	dimension int
+	records   []Record
+	norms     []float64 // vector norms, for cosine similarity
+	positions map[string]int
+}
+
+// NewFlat creates an empty flat index. The dimension is fixed by the first
+// record added.
+func NewFlat(metric Metric) *Flat {
+	return &Flat{metric: metric, positions: make(map[string]int)}
+}
+
+// Metric returns the metric the index was created with.
+func (f *Flat) Metric() Metric {
+	return f.metric
+}
+
+// Dimension returns the vector length, or zero for an empty index.
+func (f *Flat) Dimension() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.dimension
+}
+
+func (f *Flat) Add(records ...Record) error {
+	return f.insert(records, false)
+}
+
+func (f *Flat) Upsert(records ...Record) error {
+	return f.insert(records, true)
+}
+
+func (f *Flat) insert(records []Record, replace bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Validate everything first so a bad record does not leave a partial batch.
+	dimension := f.dimension
+	seen := make(map[string]bool, len(records))
+	for _, r := range records {
+		if err := checkRecord(r, dimension); err != nil {
+			return err
+		}
+		if dimension == 0 {
+			dimension = len(r.Vector)
+		}
+		if _, ok := f.positions[r.ID]; (ok && !replace) || (seen[r.ID] && !replace) {
+			return fmt.Errorf("%w: %q", ErrDuplicateID, r.ID)
+		}
+		seen[r.ID] = true
+	}
+
+	f.dimension = dimension
+	for _, r := range records {
+		r = cloneRecord(r)
+		if pos, ok := f.positions[r.ID]; ok {
+			f.records[pos] = r
+			f.norms[pos] = norm(r.Vector)
+			continue
+		}
+		f.positions[r.ID] = len(f.records)
+		f.records = append(f.records, r)
+		f.norms = append(f.norms, norm(r.Vector))
+	}
+	return nil
+}
+
+func (f *Flat) Delete(ids ...string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	deleted := 0
+	for _, id := range ids {
+		pos, ok := f.positions[id]
+		if !ok {
+			continue
+		}
+		last := len(f.records) - 1
+		f.records[pos], f.norms[pos] = f.records[last], f.norms[last]
+		f.positions[f.records[pos].ID] = pos
+		f.records[last] = Record{}
+		f.records, f.norms = f.records[:last], f.norms[:last]
+		delete(f.positions, id)
+		deleted++
+	}
+	if len(f.records) == 0 {
+		f.dimension = 0
+	}
+	return deleted
+}
+
+func (f *Flat) Get(id string) (Record, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	pos, ok := f.positions[id]
+	if !ok {
+		return Record{}, false
+	}
+	return cloneRecord(f.records[pos]), true
+}
+
+func (f *Flat) Len() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return len(f.records)
+}
+
+func (f *Flat) Search(q Query) ([]Result, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if len(f.records) == 0 {
+		return nil, nil
+	}
+	if len(q.Vector) != f.dimension {
+		return nil, fmt.Errorf("%w: query has %d dimensions, index has %d", ErrDimensionMismatch, len(q.Vector), f.dimension)
+	}
+
+	queryNorm := norm(q.Vector)
+	best := topK{k: q.TopK}
+	for i, r := range f.records {
+		if q.Filter != nil && !q.Filter(r.Metadata) {
+			continue
+		}
+		var score float64
+		if f.metric == Cosine {
+			if queryNorm != 0 && f.norms[i] != 0 {
+				score = dot(q.Vector, r.Vector) / (queryNorm * f.norms[i])
+			}
+		} else {
+			score = f.metric.Score(q.Vector, r.Vector)
+		}
+		if q.MinScore != nil && score < *q.MinScore {
+			continue
+		}
+		best.push(Result{Record: r, Score: score})
+	}
+
+	results := best.sorted()
+	for i := range results {
+		results[i].Record = cloneRecord(results[i].Record)
+	}
+	return results, nil
+}
+
+type flatSnapshot struct {
+	Metric    Metric   `json:"metric"`
+	Dimension int      `json:"dimension"`
+	Records   []Record `json:"records"`
+}
+
+// Save writes a snapshot of the index as JSON.
+func (f *Flat) Save(w io.Writer) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return json.NewEncoder(w).Encode(flatSnapshot{Metric: f.metric, Dimension: f.dimension, Records: f.records})
+}
+
+// SaveFile writes a snapshot of the index to path, replacing it atomically.
+func (f *Flat) SaveFile(path string) error {
+	return writeFileAtomic(path, f.Save)
+}
+
+// LoadFlat reads an index written by Save.
+func LoadFlat(r io.Reader) (*Flat, error) {
+	var snapshot flatSnapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return nil, err
+	}
+	f := NewFlat(snapshot.Metric)
+	if err := f.Add(snapshot.Records...); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// LoadFlatFile reads an index written by SaveFile.
+func LoadFlatFile(path string) (*Flat, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LoadFlat(file)
+}
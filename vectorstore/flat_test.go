@@ -0,0 +1,140 @@
+package vectorstore
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func newTestFlat(t *testing.T, metric Metric) *Flat {
+	t.Helper()
+	f := NewFlat(metric)
+	err := f.Add(
+		Record{ID: "x", Vector: []float64{1, 0}, Metadata: map[string]any{"lang": "go", "page": 1}},
+		Record{ID: "y", Vector: []float64{0, 1}, Metadata: map[string]any{"lang": "py", "page": 2}},
+		Record{ID: "xy", Vector: []float64{2, 2}, Metadata: map[string]any{"lang": "go", "page": 3}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func ids(results []Result) []string {
+	out := make([]string, len(results))
+	for i, r := range results {
+		out[i] = r.ID
+	}
+	return out
+}
+
+func equalIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFlatMetrics(t *testing.T) {
+	query := []float64{1, 0.1}
+	tests := []struct {
+		metric Metric
+		want   []string
+	}{
+		{Cosine, []string{"x", "xy", "y"}},
+		{DotProduct, []string{"xy", "x", "y"}},
+		{Euclidean, []string{"x", "y", "xy"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.metric.String(), func(t *testing.T) {
+			results, err := newTestFlat(t, tt.metric).Search(Query{Vector: query})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(results); !equalIDs(got, tt.want) {
+				t.Fatalf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlatTopKThresholdAndFilter(t *testing.T) {
+	f := newTestFlat(t, Cosine)
+
+	results, _ := f.Search(Query{Vector: []float64{1, 0}, TopK: 1})
+	if got := ids(results); !equalIDs(got, []string{"x"}) || results[0].Score != 1 {
+		t.Fatalf("top 1 = %v", results)
+	}
+
+	minScore := 0.5
+	results, _ = f.Search(Query{Vector: []float64{1, 0}, MinScore: &minScore})
+	if got := ids(results); !equalIDs(got, []string{"x", "xy"}) {
+		t.Fatalf("above threshold = %v", got)
+	}
+
+	results, _ = f.Search(Query{Vector: []float64{1, 0}, Filter: And(Eq("lang", "go"), Not(Eq("page", 1.0)))})
+	if got := ids(results); !equalIDs(got, []string{"xy"}) {
+		t.Fatalf("filtered = %v", got)
+	}
+
+	results, _ = f.Search(Query{Vector: []float64{1, 0}, Filter: In("page", 2, 3)})
+	if got := ids(results); !equalIDs(got, []string{"xy", "y"}) {
+		t.Fatalf("in = %v", got)
+	}
+}
+
+func TestFlatAddUpsertDelete(t *testing.T) {
+	f := newTestFlat(t, Cosine)
+
+	if err := f.Add(Record{ID: "x", Vector: []float64{1, 1}}); !errors.Is(err, ErrDuplicateID) {
+		t.Fatalf("duplicate add err = %v", err)
+	}
+	if err := f.Add(Record{ID: "z", Vector: []float64{1, 1, 1}}); !errors.Is(err, ErrDimensionMismatch) {
+		t.Fatalf("dimension err = %v", err)
+	}
+	if err := f.Upsert(Record{ID: "x", Vector: []float64{0, 1}, Metadata: map[string]any{"lang": "rust"}}); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := f.Get("x"); r.Vector[1] != 1 || r.Metadata["lang"] != "rust" {
+		t.Fatalf("upserted record = %+v", r)
+	}
+	if f.Len() != 3 {
+		t.Fatalf("len = %d", f.Len())
+	}
+
+	if n := f.Delete("x", "missing"); n != 1 {
+		t.Fatalf("deleted = %d", n)
+	}
+	if _, ok := f.Get("x"); ok || f.Len() != 2 {
+		t.Fatal("record was not deleted")
+	}
+	results, _ := f.Search(Query{Vector: []float64{0, 1}})
+	if got := ids(results); !equalIDs(got, []string{"y", "xy"}) {
+		t.Fatalf("after delete = %v", got)
+	}
+}
+
+func TestFlatSnapshot(t *testing.T) {
+	f := newTestFlat(t, Euclidean)
+	path := filepath.Join(t.TempDir(), "index.json")
+	if err := f.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadFlatFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Metric() != Euclidean || loaded.Len() != 3 || loaded.Dimension() != 2 {
+		t.Fatalf("loaded metric=%v len=%d dim=%d", loaded.Metric(), loaded.Len(), loaded.Dimension())
+	}
+	results, _ := loaded.Search(Query{Vector: []float64{2, 2}, TopK: 1, Filter: Eq("page", 3)})
+	if len(results) != 1 || results[0].ID != "xy" || math.Abs(results[0].Score) > 1e-12 {
+		t.Fatalf("results = %+v", results)
+	}
+}
@@ -0,0 +1,665 @@
+package vectorstore
+
+import (
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sync"
+)
+
+var _ Index = (*HNSW)(nil)
+
+// HNSWConfig tunes an HNSW index. Zero fields take the defaults.
+type HNSWConfig struct {
+	M              int   `json:"m"`               // neighbours per node and layer, defaults to 16; layer 0 keeps twice as many
+	EfConstruction int   `json:"ef_construction"` // candidate list size while inserting, defaults to 200
+	EfSearch       int   `json:"ef_search"`       // candidate list size while searching, defaults to 64; raised to TopK when smaller
+	Seed           int64 `json:"seed"`            // seed for level assignment, defaults to 1 so builds are reproducible
+}
+
+func (c HNSWConfig) withDefaults() HNSWConfig {
+	if c.M <= 0 {
+		c.M = 16
+	}
+	if c.EfConstruction <= 0 {
+		c.EfConstruction = 200
+	}
+	if c.EfSearch <= 0 {
+		c.EfSearch = 64
+	}
+	if c.Seed == 0 {
+		c.Seed = 1
+	}
+	return c
+}
+
+// HNSW is an approximate nearest neighbour index based on hierarchical
+// navigable small world graphs (Malkov and Yashunin, 2016). Searches take
+// roughly logarithmic time, at the price of occasionally missing a true
+// neighbour; raise EfSearch to trade speed for recall.
+//
+// Deleted records stay in the graph as tombstones that searches walk through
+// but never return; Compact rebuilds the graph without them. HNSW is safe
+// for concurrent use, and searches run in parallel with each other.
+type HNSW struct {
+	metric    Metric
+	config    HNSWConfig
+	levelMult float64
+
+	mu        sync.RWMutex
+	rng       *rand.Rand
+	dimension int
+	nodes     []*hnswNode
+	positions map[string]int32
+	entry     int32
+	maxLevel  int
+	deleted   int
+}
+
+type hnswNode struct {
+	record    Record
+	vector    []float64 // record.Vector, normalized for cosine similarity
+	neighbors [][]int32 // per layer, from 0 to the node's level
+	deleted   bool
+}
+
+// NewHNSW creates an empty HNSW index. The dimension is fixed by the first
+// record added.
+func NewHNSW(metric Metric, config HNSWConfig) *HNSW {
+	config = config.withDefaults()
+	return &HNSW{
+		metric:    metric,
+		config:    config,
+		levelMult: 1 / math.Log(float64(max(config.M, 2))),
+		rng:       rand.New(rand.NewSource(config.Seed)),
+		positions: make(map[string]int32),
+		entry:     -1,
+	}
+}
+
+// Metric returns the metric the index was created with.
+func (h *HNSW) Metric() Metric {
+	return h.metric
+}
+
+// Config returns the configuration with defaults applied.
+func (h *HNSW) Config() HNSWConfig {
+	return h.config
+}
+
+// SetEfSearch changes the candidate list size of later searches.
+func (h *HNSW) SetEfSearch(ef int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if ef > 0 {
+		h.config.EfSearch = ef
+	}
+}
+
+// Dimension returns the vector length, or zero for an empty index.
+func (h *HNSW) Dimension() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.dimension
+}
+
+func (h *HNSW) Add(records ...Record) error {
+	return h.insert(records, false)
+}
+
+func (h *HNSW) Upsert(records ...Record) error {
+	return h.insert(records, true)
+}
+
+func (h *HNSW) insert(records []Record, replace bool) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	dimension := h.dimension
+	seen := make(map[string]bool, len(records))
+	for _, r := range records {
+		if err := checkRecord(r, dimension); err != nil {
+			return err
+		}
+		if dimension == 0 {
+			dimension = len(r.Vector)
+		}
+		if _, ok := h.positions[r.ID]; (ok && !replace) || (seen[r.ID] && !replace) {
+			return fmt.Errorf("%w: %q", ErrDuplicateID, r.ID)
+		}
+		seen[r.ID] = true
+	}
+
+	h.dimension = dimension
+	for _, r := range records {
+		// A replaced record becomes a tombstone, since its old position in the
+		// graph says nothing about the new vector.
+		h.remove(r.ID)
+		h.link(cloneRecord(r), h.randomLevel())
+	}
+	return nil
+}
+
+func (h *HNSW) randomLevel() int {
+	return int(math.Floor(-math.Log(1-h.rng.Float64()) * h.levelMult))
+}
+
+// link inserts a record into the graph at level.
+func (h *HNSW) link(r Record, level int) {
+	id := int32(len(h.nodes))
+	node := &hnswNode{record: r, vector: h.prepare(r.Vector), neighbors: make([][]int32, level+1)}
+	h.nodes = append(h.nodes, node)
+	h.positions[r.ID] = id
+
+	if h.entry < 0 {
+		h.entry, h.maxLevel = id, level
+		return
+	}
+
+	entry := h.entry
+	for l := h.maxLevel; l > level; l-- {
+		entry = h.greedy(node.vector, entry, l)
+	}
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		candidates := h.searchLayer(node.vector, []int32{entry}, h.config.EfConstruction, l)
+		node.neighbors[l] = h.selectNeighbors(candidates, h.maxNeighbors(l))
+		for _, n := range node.neighbors[l] {
+			h.connect(n, id, l)
+		}
+		entry = candidates[0].id
+	}
+	if level > h.maxLevel {
+		h.entry, h.maxLevel = id, level
+	}
+}
+
+func (h *HNSW) maxNeighbors(level int) int {
+	if level == 0 {
+		return 2 * h.config.M
+	}
+	return h.config.M
+}
+
+// connect adds a link from node to neighbor, pruning the node's links when it
+// has too many.
+func (h *HNSW) connect(node, neighbor int32, level int) {
+	n := h.nodes[node]
+	n.neighbors[level] = append(n.neighbors[level], neighbor)
+	limit := h.maxNeighbors(level)
+	if len(n.neighbors[level]) <= limit {
+		return
+	}
+	candidates := make([]candidate, len(n.neighbors[level]))
+	for i, c := range n.neighbors[level] {
+		candidates[i] = candidate{id: c, dist: h.distance(n.vector, h.nodes[c].vector)}
+	}
+	sortCandidates(candidates)
+	n.neighbors[level] = h.selectNeighbors(candidates, limit)
+}
+
+// selectNeighbors picks up to m neighbours from candidates sorted nearest
+// first, preferring candidates that are closer to the new node than to any
+// neighbour already picked, so links spread in different directions. The
+// remaining slots are filled with the nearest of the skipped candidates.
+func (h *HNSW) selectNeighbors(candidates []candidate, m int) []int32 {
+	selected := make([]int32, 0, m)
+	var skipped []int32
+	for _, c := range candidates {
+		if len(selected) >= m {
+			break
+		}
+		diverse := true
+		for _, s := range selected {
+			if h.distance(h.nodes[c.id].vector, h.nodes[s].vector) < c.dist {
+				diverse = false
+				break
+			}
+		}
+		if diverse {
+			selected = append(selected, c.id)
+		} else {
+			skipped = append(skipped, c.id)
+		}
+	}
+	for _, s := range skipped {
+		if len(selected) >= m {
+			break
+		}
+		selected = append(selected, s)
+	}
+	return selected
+}
+
+// greedy walks a layer towards the node nearest to vector.
+func (h *HNSW) greedy(vector []float64, entry int32, level int) int32 {
+	best := h.distance(vector, h.nodes[entry].vector)
+	for changed := true; changed; {
+		changed = false
+		for _, n := range h.nodes[entry].neighbors[level] {
+			if d := h.distance(vector, h.nodes[n].vector); d < best {
+				best, entry, changed = d, n, true
+			}
+		}
+	}
+	return entry
+}
+
+// searchLayer returns up to ef nodes of a layer nearest to vector, nearest
+// first. Tombstones are included, since they still route the search.
+func (h *HNSW) searchLayer(vector []float64, entries []int32, ef int, level int) []candidate {
+	visited := h.visitedSet()
+	defer visitedPool.Put(visited)
+	pending := &candidateHeap{}
+	found := &candidateHeap{farthestFirst: true}
+	for _, e := range entries {
+		c := candidate{id: e, dist: h.distance(vector, h.nodes[e].vector)}
+		visited.visit(e)
+		heap.Push(pending, c)
+		heap.Push(found, c)
+	}
+
+	for pending.Len() > 0 {
+		c := heap.Pop(pending).(candidate)
+		if found.Len() >= ef && c.dist > found.items[0].dist {
+			break
+		}
+		for _, n := range h.nodes[c.id].neighbors[level] {
+			if !visited.visit(n) {
+				continue
+			}
+			d := h.distance(vector, h.nodes[n].vector)
+			if found.Len() < ef || d < found.items[0].dist {
+				heap.Push(pending, candidate{id: n, dist: d})
+				heap.Push(found, candidate{id: n, dist: d})
+				if found.Len() > ef {
+					heap.Pop(found)
+				}
+			}
+		}
+	}
+
+	out := make([]candidate, found.Len())
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(found).(candidate)
+	}
+	return out
+}
+
+func (h *HNSW) Delete(ids ...string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	deleted := 0
+	for _, id := range ids {
+		if h.remove(id) {
+			deleted++
+		}
+	}
+	if len(h.positions) == 0 {
+		h.reset()
+	}
+	return deleted
+}
+
+// remove turns a record into a tombstone.
+func (h *HNSW) remove(id string) bool {
+	pos, ok := h.positions[id]
+	if !ok {
+		return false
+	}
+	h.nodes[pos].deleted = true
+	h.nodes[pos].record.Metadata = nil
+	delete(h.positions, id)
+	h.deleted++
+	return true
+}
+
+func (h *HNSW) reset() {
+	h.dimension = 0
+	h.nodes = nil
+	h.entry, h.maxLevel, h.deleted = -1, 0, 0
+}
+
+// Tombstones returns the number of deleted records still in the graph.
+func (h *HNSW) Tombstones() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.deleted
+}
+
+// Compact rebuilds the graph without tombstones.
+func (h *HNSW) Compact() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.deleted == 0 {
+		return
+	}
+	nodes := h.nodes
+	h.positions = make(map[string]int32, len(nodes)-h.deleted)
+	h.nodes = nil
+	h.entry, h.maxLevel, h.deleted = -1, 0, 0
+	for _, n := range nodes {
+		if !n.deleted {
+			h.link(n.record, len(n.neighbors)-1)
+		}
+	}
+	if len(h.nodes) == 0 {
+		h.reset()
+	}
+}
+
+func (h *HNSW) Get(id string) (Record, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	pos, ok := h.positions[id]
+	if !ok {
+		return Record{}, false
+	}
+	return cloneRecord(h.nodes[pos].record), true
+}
+
+func (h *HNSW) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.positions)
+}
+
+// Search returns approximate nearest neighbours. Without TopK, or when the
+// filter rejects so much of the graph that the walk cannot find TopK matches,
+// it falls back to an exact scan.
+func (h *HNSW) Search(q Query) ([]Result, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.positions) == 0 {
+		return nil, nil
+	}
+	if len(q.Vector) != h.dimension {
+		return nil, fmt.Errorf("%w: query has %d dimensions, index has %d", ErrDimensionMismatch, len(q.Vector), h.dimension)
+	}
+
+	vector := h.prepare(q.Vector)
+	if q.TopK <= 0 {
+		return h.scan(vector, q), nil
+	}
+
+	entry := h.entry
+	for l := h.maxLevel; l > 0; l-- {
+		entry = h.greedy(vector, entry, l)
+	}
+	ef := max(h.config.EfSearch, q.TopK)
+	for {
+		candidates := h.searchLayer(vector, []int32{entry}, ef, 0)
+		best := topK{k: q.TopK}
+		matched := 0
+		for _, c := range candidates {
+			if r, ok := h.result(c, q); ok {
+				best.push(r)
+				matched++
+			}
+		}
+		// Fewer matches than asked for means the filter or threshold rejected
+		// part of the candidates, or the graph is smaller than TopK.
+		if matched >= q.TopK || len(candidates) < ef {
+			return best.sorted(), nil
+		}
+		if q.MinScore != nil && matched > 0 && candidates[len(candidates)-1].dist > h.distanceFor(*q.MinScore) {
+			// Every further node scores below the threshold.
+			return best.sorted(), nil
+		}
+		if ef >= len(h.nodes)/2 {
+			return h.scan(vector, q), nil
+		}
+		ef *= 2
+	}
+}
+
+// scan is the exact fallback of Search.
+func (h *HNSW) scan(vector []float64, q Query) []Result {
+	best := topK{k: q.TopK}
+	for i, n := range h.nodes {
+		if n.deleted {
+			continue
+		}
+		if r, ok := h.result(candidate{id: int32(i), dist: h.distance(vector, n.vector)}, q); ok {
+			best.push(r)
+		}
+	}
+	return best.sorted()
+}
+
+func (h *HNSW) result(c candidate, q Query) (Result, bool) {
+	n := h.nodes[c.id]
+	if n.deleted || (q.Filter != nil && !q.Filter(n.record.Metadata)) {
+		return Result{}, false
+	}
+	score := h.score(c.dist)
+	if q.MinScore != nil && score < *q.MinScore {
+		return Result{}, false
+	}
+	return Result{Record: cloneRecord(n.record), Score: score}, true
+}
+
+// prepare returns the vector used inside the graph.
+func (h *HNSW) prepare(v []float64) []float64 {
+	if h.metric != Cosine {
+		return v
+	}
+	n := norm(v)
+	out := make([]float64, len(v))
+	if n == 0 {
+		return out
+	}
+	for i, x := range v {
+		out[i] = x / n
+	}
+	return out
+}
+
+// distance orders graph vectors; lower is closer.
+func (h *HNSW) distance(a, b []float64) float64 {
+	switch h.metric {
+	case DotProduct:
+		return -dot(a, b)
+	case Euclidean:
+		return squaredDistance(a, b)
+	default:
+		return 1 - dot(a, b)
+	}
+}
+
+// score converts a distance into the score of Metric.Score.
+func (h *HNSW) score(dist float64) float64 {
+	switch h.metric {
+	case DotProduct:
+		return -dist
+	case Euclidean:
+		return -math.Sqrt(dist)
+	default:
+		return 1 - dist
+	}
+}
+
+// distanceFor is the inverse of score.
+func (h *HNSW) distanceFor(score float64) float64 {
+	switch h.metric {
+	case DotProduct:
+		return -score
+	case Euclidean:
+		if score > 0 {
+			return 0
+		}
+		return score * score
+	default:
+		return 1 - score
+	}
+}
+
+// visited marks the nodes seen by one searchLayer call. Sets are pooled and
+// cleared by bumping a generation counter rather than reallocated.
+type visited struct {
+	marks      []uint32
+	generation uint32
+}
+
+var visitedPool sync.Pool
+
+func (h *HNSW) visitedSet() *visited {
+	v, _ := visitedPool.Get().(*visited)
+	if v == nil {
+		v = &visited{}
+	}
+	if len(v.marks) < len(h.nodes) {
+		v.marks = make([]uint32, len(h.nodes)+len(h.nodes)/4)
+		v.generation = 0
+	}
+	v.generation++
+	if v.generation == 0 {
+		clear(v.marks)
+		v.generation = 1
+	}
+	return v
+}
+
+// visit marks id and reports whether it was unmarked.
+func (v *visited) visit(id int32) bool {
+	if v.marks[id] == v.generation {
+		return false
+	}
+	v.marks[id] = v.generation
+	return true
+}
+
+type candidate struct {
+	id   int32
+	dist float64
+}
+
+func sortCandidates(c []candidate) {
+	h := candidateHeap{items: c}
+	heap.Init(&h)
+	sorted := make([]candidate, 0, len(c))
+	for h.Len() > 0 {
+		sorted = append(sorted, heap.Pop(&h).(candidate))
+	}
+	copy(c, sorted)
+}
+
+// candidateHeap is a min-heap on distance, or a max-heap if farthestFirst.
+type candidateHeap struct {
+	items         []candidate
+	farthestFirst bool
+}
+
+func (h candidateHeap) Len() int { return len(h.items) }
+func (h candidateHeap) Less(i, j int) bool {
+	if h.farthestFirst {
+		return h.items[i].dist > h.items[j].dist
+	}
+	return h.items[i].dist < h.items[j].dist
+}
+func (h candidateHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *candidateHeap) Push(x any)   { h.items = append(h.items, x.(candidate)) }
+func (h *candidateHeap) Pop() any {
+	c := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return c
+}
+
+type hnswSnapshot struct {
+	Metric    Metric             `json:"metric"`
+	Config    HNSWConfig         `json:"config"`
+	Dimension int                `json:"dimension"`
+	Entry     int32              `json:"entry"`
+	MaxLevel  int                `json:"max_level"`
+	Nodes     []hnswNodeSnapshot `json:"nodes"`
+}
+
+type hnswNodeSnapshot struct {
+	Record
+	Neighbors [][]int32 `json:"neighbors"`
+	Deleted   bool      `json:"deleted,omitempty"`
+}
+
+// Save writes the index, graph included, as JSON, so loading it does not
+// rebuild the graph.
+func (h *HNSW) Save(w io.Writer) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	snapshot := hnswSnapshot{
+		Metric:    h.metric,
+		Config:    h.config,
+		Dimension: h.dimension,
+		Entry:     h.entry,
+		MaxLevel:  h.maxLevel,
+		Nodes:     make([]hnswNodeSnapshot, len(h.nodes)),
+	}
+	for i, n := range h.nodes {
+		snapshot.Nodes[i] = hnswNodeSnapshot{Record: n.record, Neighbors: n.neighbors, Deleted: n.deleted}
+	}
+	return json.NewEncoder(w).Encode(snapshot)
+}
+
+// SaveFile writes the index to path, replacing it atomically.
+func (h *HNSW) SaveFile(path string) error {
+	return writeFileAtomic(path, h.Save)
+}
+
+// LoadHNSW reads an index written by Save.
+func LoadHNSW(r io.Reader) (*HNSW, error) {
+	var snapshot hnswSnapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return nil, err
+	}
+	h := NewHNSW(snapshot.Metric, snapshot.Config)
+	// Continue the level sequence differently from a fresh index, so levels
+	// of later inserts do not repeat those of the first ones.
+	h.rng = rand.New(rand.NewSource(h.config.Seed + int64(len(snapshot.Nodes))))
+	h.dimension = snapshot.Dimension
+	h.entry = snapshot.Entry
+	h.maxLevel = snapshot.MaxLevel
+	h.nodes = make([]*hnswNode, len(snapshot.Nodes))
+	for i, s := range snapshot.Nodes {
+		if len(s.Vector) != h.dimension || len(s.Neighbors) == 0 || len(s.Neighbors) > h.maxLevel+1 {
+			return nil, fmt.Errorf("vectorstore: corrupt node %d", i)
+		}
+		for level, layer := range s.Neighbors {
+			for _, n := range layer {
+				if n < 0 || int(n) >= len(snapshot.Nodes) {
+					return nil, fmt.Errorf("vectorstore: node %d links to missing node %d", i, n)
+				}
+				// searchLayer follows the link into the neighbour's own list
+				// for this layer.
+				if len(snapshot.Nodes[n].Neighbors) <= level {
+					return nil, fmt.Errorf("vectorstore: node %d links to node %d on layer %d above its level", i, n, level)
+				}
+			}
+		}
+		h.nodes[i] = &hnswNode{record: s.Record, vector: h.prepare(s.Vector), neighbors: s.Neighbors, deleted: s.Deleted}
+		if s.Deleted {
+			h.deleted++
+			continue
+		}
+		if _, ok := h.positions[s.ID]; ok {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateID, s.ID)
+		}
+		h.positions[s.ID] = int32(i)
+	}
+	if len(h.nodes) > 0 && (h.entry < 0 || int(h.entry) >= len(h.nodes) || len(h.nodes[h.entry].neighbors) != h.maxLevel+1) {
+		return nil, errors.New("vectorstore: corrupt entry point")
+	}
+	return h, nil
+}
+
+// LoadHNSWFile reads an index written by SaveFile.
+func LoadHNSWFile(path string) (*HNSW, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LoadHNSW(file)
+}
@@ -0,0 +1,215 @@
+package vectorstore
+
+import (
+	"bytes"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func randomRecords(rng *rand.Rand, n, dim int) []Record {
+	records := make([]Record, n)
+	for i := range records {
+		v := make([]float64, dim)
+		for j := range v {
+			v[j] = rng.NormFloat64()
+		}
+		records[i] = Record{ID: strconv.Itoa(i), Vector: v, Metadata: map[string]any{"shard": i % 4}}
+	}
+	return records
+}
+
+// recall returns the fraction of the exact top k found by the index.
+func recall(t testing.TB, index Index, exact *Flat, queries []Record, k int, filter Filter) float64 {
+	found, total := 0, 0
+	for _, q := range queries {
+		want, err := exact.Search(Query{Vector: q.Vector, TopK: k, Filter: filter})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := index.Search(Query{Vector: q.Vector, TopK: k, Filter: filter})
+		if err != nil {
+			t.Fatal(err)
+		}
+		hits := make(map[string]bool, len(got))
+		for _, r := range got {
+			hits[r.ID] = true
+		}
+		for _, r := range want {
+			if hits[r.ID] {
+				found++
+			}
+		}
+		total += len(want)
+	}
+	return float64(found) / float64(total)
+}
+
+func buildIndexes(t testing.TB, metric Metric, n, dim int) (*HNSW, *Flat, []Record) {
+	rng := rand.New(rand.NewSource(42))
+	records := randomRecords(rng, n, dim)
+	h := NewHNSW(metric, HNSWConfig{M: 12, EfConstruction: 100})
+	f := NewFlat(metric)
+	if err := h.Add(records...); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Add(records...); err != nil {
+		t.Fatal(err)
+	}
+	return h, f, randomRecords(rng, 50, dim)
+}
+
+func TestHNSWRecall(t *testing.T) {
+	for _, metric := range []Metric{Cosine, DotProduct, Euclidean} {
+		t.Run(metric.String(), func(t *testing.T) {
+			h, f, queries := buildIndexes(t, metric, 2000, 16)
+			if r := recall(t, h, f, queries, 10, nil); r < 0.9 {
+				t.Fatalf("recall@10 = %.3f, want >= 0.9", r)
+			}
+			if r := recall(t, h, f, queries, 10, Eq("shard", 1)); r < 0.9 {
+				t.Fatalf("filtered recall@10 = %.3f, want >= 0.9", r)
+			}
+		})
+	}
+}
+
+func TestHNSWScoresMatchFlat(t *testing.T) {
+	h, f, queries := buildIndexes(t, Euclidean, 200, 8)
+	got, _ := h.Search(Query{Vector: queries[0].Vector, TopK: 1})
+	want, _ := f.Search(Query{Vector: queries[0].Vector, TopK: 1})
+	if got[0].ID != want[0].ID || got[0].Score != want[0].Score {
+		t.Fatalf("hnsw = %s %v, flat = %s %v", got[0].ID, got[0].Score, want[0].ID, want[0].Score)
+	}
+
+	minScore := want[0].Score - 1
+	all, _ := h.Search(Query{Vector: queries[0].Vector, MinScore: &minScore})
+	exact, _ := f.Search(Query{Vector: queries[0].Vector, MinScore: &minScore})
+	if len(all) != len(exact) {
+		t.Fatalf("threshold search returned %d results, want %d", len(all), len(exact))
+	}
+}
+
+func TestHNSWDeleteUpsertCompact(t *testing.T) {
+	h, _, _ := buildIndexes(t, Cosine, 300, 8)
+
+	target, _ := h.Get("7")
+	if n := h.Delete("7", "8", "missing"); n != 2 {
+		t.Fatalf("deleted = %d", n)
+	}
+	results, _ := h.Search(Query{Vector: target.Vector, TopK: 5})
+	for _, r := range results {
+		if r.ID == "7" || r.ID == "8" {
+			t.Fatalf("deleted record %s returned", r.ID)
+		}
+	}
+
+	if err := h.Upsert(Record{ID: "9", Vector: target.Vector}); err != nil {
+		t.Fatal(err)
+	}
+	results, _ = h.Search(Query{Vector: target.Vector, TopK: 1})
+	if results[0].ID != "9" || h.Len() != 298 || h.Tombstones() != 3 {
+		t.Fatalf("after upsert top = %s len = %d tombstones = %d", results[0].ID, h.Len(), h.Tombstones())
+	}
+
+	h.Compact()
+	if h.Tombstones() != 0 || h.Len() != 298 {
+		t.Fatalf("after compact len = %d tombstones = %d", h.Len(), h.Tombstones())
+	}
+	results, _ = h.Search(Query{Vector: target.Vector, TopK: 1})
+	if results[0].ID != "9" {
+		t.Fatalf("after compact top = %s", results[0].ID)
+	}
+}
+
+func TestHNSWSnapshot(t *testing.T) {
+	h, f, queries := buildIndexes(t, Cosine, 500, 8)
+	h.Delete("3")
+	f.Delete("3")
+
+	var buf bytes.Buffer
+	if err := h.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadHNSW(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != h.Len() || loaded.Config() != h.Config() {
+		t.Fatalf("loaded len = %d config = %+v", loaded.Len(), loaded.Config())
+	}
+	if r := recall(t, loaded, f, queries, 10, nil); r < 0.9 {
+		t.Fatalf("recall after load = %.3f", r)
+	}
+	if err := loaded.Add(Record{ID: "new", Vector: queries[0].Vector}); err != nil {
+		t.Fatal(err)
+	}
+	if results, _ := loaded.Search(Query{Vector: queries[0].Vector, TopK: 1}); results[0].ID != "new" {
+		t.Fatalf("top after insert = %s", results[0].ID)
+	}
+}
+
+func TestHNSWConcurrentReadsAndWrites(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	records := randomRecords(rng, 400, 8)
+	h := NewHNSW(Cosine, HNSWConfig{})
+	h.Add(records[:100]...)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for _, q := range records[i*10 : i*10+10] {
+				if _, err := h.Search(Query{Vector: q.Vector, TopK: 5}); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	for _, r := range records[100:] {
+		if err := h.Add(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+	if h.Len() != 400 {
+		t.Fatalf("len = %d", h.Len())
+	}
+}
+
+func benchmarkSearch(b *testing.B, index Index, exact *Flat, queries []Record) {
+	r := recall(b, index, exact, queries, 10, nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.Search(Query{Vector: queries[i%len(queries)].Vector, TopK: 10})
+	}
+	b.ReportMetric(r, "recall@10")
+}
+
+func BenchmarkFlatSearch(b *testing.B) {
+	_, f, queries := buildIndexes(b, Cosine, 20000, 64)
+	benchmarkSearch(b, f, f, queries)
+}
+
+func BenchmarkHNSWSearch(b *testing.B) {
+	h, f, queries := buildIndexes(b, Cosine, 20000, 64)
+	for _, ef := range []int{32, 64, 256} {
+		b.Run("ef="+strconv.Itoa(ef), func(b *testing.B) {
+			h.SetEfSearch(ef)
+			benchmarkSearch(b, h, f, queries)
+		})
+	}
+}
+
+func TestLoadHNSWRejectsLinkAboveLevel(t *testing.T) {
+	// Node 0 is on layers 0 and 1, node 1 only on layer 0, yet node 0 links
+	// to node 1 on layer 1.
+	snapshot := `{"metric":"cosine","dimension":2,"entry":0,"max_level":1,"nodes":[
+		{"id":"a","vector":[1,0],"neighbors":[[1],[1]]},
+		{"id":"b","vector":[0,1],"neighbors":[[0]]}]}`
+	if _, err := LoadHNSW(strings.NewReader(snapshot)); err == nil || !strings.Contains(err.Error(), "above its level") {
+		t.Fatalf("err = %v", err)
+	}
+}
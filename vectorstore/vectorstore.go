@@ -0,0 +1,317 @@
+// Package vectorstore stores embedding vectors with metadata and finds the
+// records nearest to a query vector, so small retrieval services can run
+// without an external database.
+package vectorstore
+
+import (
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+// Metric selects how vectors are compared.
+type Metric int
+
+const (
+	Cosine     Metric = iota // cosine similarity
+	DotProduct               // inner product, for vectors normalized by the model
+	Euclidean                // L2 distance
+)
+
+func (m Metric) String() string {
+	switch m {
+	case Cosine:
+		return "cosine"
+	case DotProduct:
+		return "dot"
+	case Euclidean:
+		return "l2"
+	}
+	return fmt.Sprintf("Metric(%d)", int(m))
+}
+
+func (m Metric) MarshalText() ([]byte, error) {
+	switch m {
+	case Cosine, DotProduct, Euclidean:
+		return []byte(m.String()), nil
+	}
+	return nil, fmt.Errorf("vectorstore: unknown metric %d", int(m))
+}
+
+func (m *Metric) UnmarshalText(text []byte) error {
+	for _, candidate := range []Metric{Cosine, DotProduct, Euclidean} {
+		if string(text) == candidate.String() {
+			*m = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("vectorstore: unknown metric %q", text)
+}
+
+// Score compares two vectors of equal length. Higher is always closer: the
+// Euclidean score is the negated distance.
+func (m Metric) Score(a, b []float64) float64 {
+	switch m {
+	case DotProduct:
+		return dot(a, b)
+	case Euclidean:
+		return -math.Sqrt(squaredDistance(a, b))
+	default:
+		na, nb := norm(a), norm(b)
+		if na == 0 || nb == 0 {
+			return 0
+		}
+		return dot(a, b) / (na * nb)
+	}
+}
+
+var (
+	ErrDuplicateID       = errors.New("vectorstore: duplicate id")
+	ErrEmptyID           = errors.New("vectorstore: empty id")
+	ErrDimensionMismatch = errors.New("vectorstore: dimension mismatch")
+)
+
+// Record is a vector with its ID and optional metadata.
+type Record struct {
+	ID       string         `json:"id"`
+	Vector   []float64      `json:"vector"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// Result is a record matching a query.
+type Result struct {
+	Record
+	Score float64 `json:"score"` // see Metric.Score
+}
+
+// Query describes a similarity search.
+type Query struct {
+	Vector   []float64
+	TopK     int      // Optional: maximum number of results, all matches if zero
+	MinScore *float64 // Optional: drop results scoring below this
+	Filter   Filter   // Optional: only consider records whose metadata matches
+}
+
+// Index is implemented by Flat, for exact search, and HNSW, for approximate
+// search over large collections.
+type Index interface {
+	// Add inserts records, failing if an ID already exists.
+	Add(records ...Record) error
+	// Upsert inserts records, replacing those with the same ID.
+	Upsert(records ...Record) error
+	// Delete removes records and returns how many existed.
+	Delete(ids ...string) int
+	Get(id string) (Record, bool)
+	Len() int
+	// Search returns the best matching records, best first.
+	Search(q Query) ([]Result, error)
+}
+
+// Filter selects records by metadata.
+type Filter func(metadata map[string]any) bool
+
+// Eq matches records whose metadata key equals value. Numbers compare by
+// value, so filters still match after a snapshot round trip.
+func Eq(key string, value any) Filter {
+	return func(metadata map[string]any) bool {
+		v, ok := metadata[key]
+		return ok && equalValues(v, value)
+	}
+}
+
+// In matches records whose metadata key equals one of values.
+func In(key string, values ...any) Filter {
+	return func(metadata map[string]any) bool {
+		v, ok := metadata[key]
+		if !ok {
+			return false
+		}
+		for _, value := range values {
+			if equalValues(v, value) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Exists matches records that have metadata key.
+func Exists(key string) Filter {
+	return func(metadata map[string]any) bool {
+		_, ok := metadata[key]
+		return ok
+	}
+}
+
+// And matches records matching all filters.
+func And(filters ...Filter) Filter {
+	return func(metadata map[string]any) bool {
+		for _, f := range filters {
+			if !f(metadata) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or matches records matching any of filters.
+func Or(filters ...Filter) Filter {
+	return func(metadata map[string]any) bool {
+		for _, f := range filters {
+			if f(metadata) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not matches records not matching f.
+func Not(f Filter) Filter {
+	return func(metadata map[string]any) bool { return !f(metadata) }
+}
+
+func equalValues(a, b any) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func dot(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func norm(a []float64) float64 {
+	return math.Sqrt(dot(a, a))
+}
+
+func squaredDistance(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return sum
+}
+
+func checkRecord(r Record, dim int) error {
+	if r.ID == "" {
+		return ErrEmptyID
+	}
+	if len(r.Vector) == 0 || (dim != 0 && len(r.Vector) != dim) {
+		return fmt.Errorf("%w: record %q has %d dimensions, index has %d", ErrDimensionMismatch, r.ID, len(r.Vector), dim)
+	}
+	return nil
+}
+
+func cloneRecord(r Record) Record {
+	r.Vector = append([]float64(nil), r.Vector...)
+	if r.Metadata != nil {
+		metadata := make(map[string]any, len(r.Metadata))
+		for k, v := range r.Metadata {
+			metadata[k] = v
+		}
+		r.Metadata = metadata
+	}
+	return r
+}
+
+// topK keeps the k best results seen so far.
+type topK struct {
+	k       int
+	results resultHeap
+}
+
+func (t *topK) push(r Result) {
+	if t.k <= 0 || len(t.results) < t.k {
+		heap.Push(&t.results, r)
+		return
+	}
+	if r.Score > t.results[0].Score {
+		t.results[0] = r
+		heap.Fix(&t.results, 0)
+	}
+}
+
+// sorted returns the results best first.
+func (t *topK) sorted() []Result {
+	out := make([]Result, len(t.results))
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(&t.results).(Result)
+	}
+	return out
+}
+
+// resultHeap is a min-heap on Score.
+type resultHeap []Result
+
+func (h resultHeap) Len() int           { return len(h) }
+func (h resultHeap) Less(i, j int) bool { return h[i].Score < h[j].Score }
+func (h resultHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *resultHeap) Push(x any)        { *h = append(*h, x.(Result)) }
+func (h *resultHeap) Pop() any {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
+
+// writeFileAtomic writes a file through a temporary file in the same
+// directory, so a crash never leaves a truncated snapshot behind.
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}